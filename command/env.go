@@ -18,4 +18,10 @@ type Env struct {
 	// Defaults to the public Terraform registry.
 	// To use the public OpenTofu registry, set this to `https://registry.opentofu.org/`.
 	TFRegistryBaseURL string `envconfig:"TFREGISTRY_BASE_URL" default:"https://registry.terraform.io/"`
+	// TFUpdateCacheDir is a directory to persist hash values of provider
+	// packages for the lock command. It can be overridden by the --cache-dir flag.
+	TFUpdateCacheDir string `envconfig:"TFUPDATE_CACHE_DIR"`
+	// TFPluginCacheDir is a plugin cache directory of Terraform.
+	// The lock command looks up provider packages in it before downloading.
+	TFPluginCacheDir string `envconfig:"TF_PLUGIN_CACHE_DIR"`
 }
@@ -0,0 +1,89 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minamijoyo/tfupdate/tfregistry"
+	"github.com/minamijoyo/tfupdate/tfupdate"
+	flag "github.com/spf13/pflag"
+)
+
+// InventoryCommand is a command which dumps all version constraints as JSON.
+type InventoryCommand struct {
+	Meta
+	path        string
+	recursive   bool
+	ignorePaths []string
+}
+
+// jsonInventory is a JSON representation of an inventory of dependencies.
+type jsonInventory struct {
+	// Dependencies is a list of version constraints found in all files.
+	Dependencies []tfupdate.Dependency `json:"dependencies"`
+}
+
+// Run runs the procedure of this command.
+func (c *InventoryCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("inventory", flag.ContinueOnError)
+	cmdFlags.BoolVarP(&c.recursive, "recursive", "r", false, "Check a directory recursively")
+	cmdFlags.StringArrayVarP(&c.ignorePaths, "ignore-path", "i", []string{}, "A regular expression for path to ignore")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	c.path = cmdFlags.Arg(0)
+
+	option, err := tfupdate.NewOption("", "", "", []string{}, tfregistry.Config{}, tfupdate.UpdatePolicy{}, tfupdate.VersionPolicy{}, tfupdate.LockPolicy{}, tfupdate.WalkPolicy{Recursive: c.recursive, IgnorePaths: c.ignorePaths})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	deps, err := tfupdate.CollectDependencies(c.Fs, option, c.path)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	out, err := marshalJSON(jsonInventory{Dependencies: deps})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("failed to encode inventory as JSON: %s", err))
+		return 1
+	}
+	c.UI.Output(out)
+
+	return 0
+}
+
+// Help returns long-form help text.
+func (c *InventoryCommand) Help() string {
+	helpText := `
+Usage: tfupdate inventory [options] <PATH>
+
+Arguments
+  PATH               A path of file or directory to inspect
+
+Options:
+  -r  --recursive    Check a directory recursively (default: false)
+  -i  --ignore-path  A regular expression for path to ignore
+                     If you want to ignore multiple directories, set the flag multiple times.
+
+Dump all version constraints of terraform, opentofu, providers and modules as
+JSON. It never writes any files.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *InventoryCommand) Synopsis() string {
+	return "Dump all version constraints as JSON"
+}
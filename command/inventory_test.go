@@ -0,0 +1,33 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/spf13/afero"
+)
+
+func TestInventoryCommandJSON(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	src := `
+terraform {
+  required_version = ">= 1.5, < 2.0"
+}
+`
+	if err := afero.WriteFile(fs, "main.tf", []byte(src), 0644); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+
+	ui := cli.NewMockUi()
+	c := &InventoryCommand{Meta: Meta{UI: ui, Fs: fs}}
+	if code := c.Run([]string{"main.tf"}); code != 0 {
+		t.Fatalf("Run() returns %d: %s", code, ui.ErrorWriter.String())
+	}
+
+	got := ui.OutputWriter.String()
+	want := `"version": ">= 1.5, < 2.0"`
+	if !strings.Contains(got, want) {
+		t.Errorf("Run() outputs %s, but want to contain %s", got, want)
+	}
+}
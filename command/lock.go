@@ -20,6 +20,16 @@ type LockCommand struct {
 	path        string
 	recursive   bool
 	ignorePaths []string
+	jobs        int
+	keepGoing   bool
+	prune       bool
+	create      bool
+	cacheDir    string
+	parallelism int
+	verify      bool
+	check       bool
+	diff        bool
+	format      string
 }
 
 // Run runs the procedure of this command.
@@ -28,12 +38,55 @@ func (c *LockCommand) Run(args []string) int {
 	cmdFlags.StringArrayVar(&c.platforms, "platform", []string{}, "A target platform for dependency lock file")
 	cmdFlags.BoolVarP(&c.recursive, "recursive", "r", false, "Check a directory recursively")
 	cmdFlags.StringArrayVarP(&c.ignorePaths, "ignore-path", "i", []string{}, "A regular expression for path to ignore")
+	cmdFlags.IntVarP(&c.jobs, "jobs", "j", 1, "A number of modules processed concurrently in a recursive mode")
+	cmdFlags.BoolVar(&c.keepGoing, "keep-going", false, "Continue updating other files when a file fails to update")
+	cmdFlags.BoolVar(&c.prune, "prune", false, "Remove provider blocks which are no longer required from lock files")
+	cmdFlags.BoolVar(&c.create, "create", false, "Create a lock file in each root module which has pinned providers but no lock file")
+	cmdFlags.StringVar(&c.cacheDir, "cache-dir", "", "A directory to persist hash values of provider packages")
+	cmdFlags.IntVar(&c.parallelism, "parallelism", 4, "A maximum number of provider packages downloaded concurrently")
+	cmdFlags.BoolVar(&c.verify, "verify", false, "Verify lock files are consistent with the configuration without updating them")
+	cmdFlags.BoolVar(&c.check, "check", false, "Check whether updates are pending without writing files")
+	cmdFlags.BoolVar(&c.diff, "diff", false, "Show a unified diff of updated files")
+	cmdFlags.StringVar(&c.format, "format", "text", "An output format. Valid values are \"text\" or \"json\"")
 
 	if err := cmdFlags.Parse(args); err != nil {
 		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
 		return 1
 	}
 
+	if err := validateJobsOption(c.jobs); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := validateOutputOptions(c.diff, c.format); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if c.parallelism < 1 {
+		c.UI.Error(fmt.Sprintf("The --parallelism option should be a positive integer, but got %d", c.parallelism))
+		return 1
+	}
+
+	if c.verify && (c.check || c.diff) {
+		c.UI.Error("The --verify option cannot be used with --check or --diff")
+		return 1
+	}
+
+	if c.verify && (c.prune || c.create || c.cacheDir != "" || cmdFlags.Changed("parallelism")) {
+		// These options only affect updating lock files.
+		c.UI.Error("The --verify option cannot be used with --prune, --create, --cache-dir or --parallelism")
+		return 1
+	}
+
+	if c.verify && c.jobs > 1 {
+		// Verifying is fast enough without concurrency because it never calls
+		// the registry.
+		c.UI.Error("The --verify option cannot be used with --jobs greater than 1")
+		return 1
+	}
+
 	if len(cmdFlags.Args()) != 1 {
 		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
 		c.UI.Error(c.Help())
@@ -63,27 +116,97 @@ func (c *LockCommand) Run(args []string) int {
 		BaseURL: env.TFRegistryBaseURL,
 	}
 
-	option, err := tfupdate.NewOption("lock", "", "", c.platforms, c.recursive, c.ignorePaths, "", tfregistryConfig)
+	cacheDir := c.cacheDir
+	if cacheDir == "" {
+		cacheDir = env.TFUpdateCacheDir
+	}
+
+	// Look up provider packages already installed locally before downloading.
+	pluginDirs := []string{}
+	if env.TFPluginCacheDir != "" {
+		pluginDirs = append(pluginDirs, env.TFPluginCacheDir)
+	}
+
+	option, err := tfupdate.NewOption("lock", "", "", c.platforms, tfregistryConfig, tfupdate.UpdatePolicy{}, tfupdate.VersionPolicy{}, tfupdate.LockPolicy{Prune: c.prune, Create: c.create, CacheDir: cacheDir, PluginDirs: pluginDirs, Parallelism: c.parallelism}, tfupdate.WalkPolicy{Recursive: c.recursive, IgnorePaths: c.ignorePaths, Jobs: c.jobs, KeepGoing: c.keepGoing})
 	if err != nil {
 		c.UI.Error(err.Error())
 		return 1
 	}
 
-	gc, err := tfupdate.NewGlobalContext(c.Fs, option)
+	gc, err := tfupdate.NewGlobalContext(c.updateFs(c.check), option)
 	if err != nil {
 		c.UI.Error(err.Error())
 		return 1
 	}
 
+	if c.verify {
+		return c.verifyLockFiles(gc)
+	}
+
 	err = tfupdate.UpdateFileOrDir(context.Background(), gc, c.path)
 	if err != nil {
 		c.UI.Error(err.Error())
 		return 1
 	}
 
+	return c.outputResults(gc, c.check, c.diff, c.format)
+}
+
+// verifyLockFiles verifies lock files and outputs issues found.
+// It returns 2 if any issues are found.
+// If any files failed to verify with the --keep-going option, it outputs a
+// summary of failures and returns 1.
+func (c *LockCommand) verifyLockFiles(gc *tfupdate.GlobalContext) int {
+	issues, err := tfupdate.VerifyLockFileOrDir(gc, c.path)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	failures := gc.Failures()
+
+	if c.format == "json" {
+		report := jsonLockIssues{Issues: issues}
+		for _, f := range failures {
+			report.Failures = append(report.Failures, jsonFailure{Filename: f.Filename, Error: f.Err.Error()})
+		}
+
+		out, err := marshalJSON(report)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("failed to encode issues as JSON: %s", err))
+			return 1
+		}
+		c.UI.Output(out)
+	} else {
+		for _, i := range issues {
+			c.UI.Output(i.String())
+		}
+	}
+
+	if len(failures) != 0 {
+		c.UI.Error(fmt.Sprintf("failed to verify %d file(s):", len(failures)))
+		for _, f := range failures {
+			c.UI.Error(fmt.Sprintf("  %s: %s", f.Filename, f.Err))
+		}
+		return 1
+	}
+
+	if len(issues) != 0 {
+		return 2
+	}
+
 	return 0
 }
 
+// jsonLockIssues is a JSON representation of the result of verifying lock files.
+type jsonLockIssues struct {
+	// Issues is a list of inconsistencies found in lock files.
+	Issues []tfupdate.LockIssue `json:"issues"`
+
+	// Failures is a list of files which failed to verify.
+	// It is only reported with the --keep-going option.
+	Failures []jsonFailure `json:"failures,omitempty"`
+}
+
 // Help returns long-form help text.
 func (c *LockCommand) Help() string {
 	helpText := `
@@ -102,6 +225,35 @@ Options:
   -r  --recursive    Check a directory recursively (default: false)
   -i  --ignore-path  A regular expression for path to ignore
                      If you want to ignore multiple directories, set the flag multiple times.
+  -j  --jobs         A number of modules processed concurrently in a recursive mode (default: 1)
+                     If greater than 1, an error in a module doesn't stop processing the others,
+                     and all errors are reported at the end.
+      --keep-going   Continue updating other files when a file fails to update (default: false)
+                     Failed files are reported at the end, and the command exits with status 1 if any.
+      --prune        Remove provider blocks which are no longer required from lock files (default: false)
+                     Providers required by local child modules are kept. If the module calls remote modules,
+                     pruning is skipped with a warning because providers required by them are unknown.
+      --create       Create a lock file in each root module which has pinned providers but no lock file (default: false)
+                     Modules called as a local module (e.g. source = "./modules/foo") by others in the PATH
+                     are regarded as child modules and skipped.
+      --cache-dir    A directory to persist hash values of provider packages across runs
+                     Provider packages already seen are not downloaded again, which also works offline.
+                     Defaults to the TFUPDATE_CACHE_DIR environment variable. If empty, the cache is disabled.
+      --parallelism  A maximum number of provider packages downloaded concurrently (default: 4)
+                     Concurrent requests for the same provider version are downloaded only once.
+      --verify       Verify lock files are consistent with the configuration without updating them (default: false)
+                     Report missing lock files in root modules, missing providers, version mismatches and
+                     missing hashes, and exit with status 2 if any. It never calls the registry.
+                     Since the lock file doesn't record a platform for each hash, hashes are only checked by
+                     the number of h1 hashes, which should be at least the number of platforms.
+                     Options only for updating, such as --prune, --create or --jobs, cannot be used together.
+                     With --format json, a list of issues is output as JSON.
+      --check        Check whether updates are pending without writing files (default: false)
+                     List files to be updated and exit with status 2 if any.
+      --diff         Show a unified diff of updated files (default: false)
+                     Use with --check to preview changes without writing files.
+      --format       An output format. Valid values are "text" or "json" (default: text)
+                     The json format outputs a list of changed attributes in all files.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -0,0 +1,77 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/spf13/afero"
+)
+
+func TestLockCommandVerifyJSON(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"main.tf": `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = ">= 3.2, < 4.0"
+    }
+  }
+}
+`,
+		".terraform.lock.hcl": `
+provider "registry.terraform.io/hashicorp/null" {
+  version = "3.1.1"
+  hashes = [
+    "h1:YvH6gTaQzGdNv+SKTZujU1O0bO+Pw6vJHOPhqgN8XNs=",
+  ]
+}
+`,
+	}
+	for filename, src := range files {
+		if err := afero.WriteFile(fs, filename, []byte(src), 0644); err != nil {
+			t.Fatalf("failed to write file: %s", err)
+		}
+	}
+
+	ui := cli.NewMockUi()
+	c := &LockCommand{Meta: Meta{UI: ui, Fs: fs}}
+	if code := c.Run([]string{"--platform", "linux_amd64", "--verify", "--format", "json", "."}); code != 2 {
+		t.Fatalf("Run() returns %d, but want = 2: %s", code, ui.ErrorWriter.String())
+	}
+
+	got := ui.OutputWriter.String()
+	want := `"message": "provider registry.terraform.io/hashicorp/null is locked to \"3.1.1\", but the configuration requires \">= 3.2.0, < 4.0.0\""`
+	if !strings.Contains(got, want) {
+		t.Errorf("Run() outputs %s, but want to contain %s", got, want)
+	}
+}
+
+func TestLockCommandVerifyIncompatibleOptions(t *testing.T) {
+	cases := []struct {
+		args []string
+	}{
+		{args: []string{"--verify", "--check", "."}},
+		{args: []string{"--verify", "--prune", "."}},
+		{args: []string{"--verify", "--create", "."}},
+		{args: []string{"--verify", "--cache-dir", "tmp", "."}},
+		{args: []string{"--verify", "--parallelism", "4", "."}},
+		{args: []string{"--verify", "-r", "--jobs", "2", "."}},
+	}
+
+	for _, tc := range cases {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := &LockCommand{Meta: Meta{UI: ui, Fs: afero.NewMemMapFs()}}
+			if code := c.Run(tc.args); code != 1 {
+				t.Errorf("Run() with args = %#v returns %d, but want = 1", tc.args, code)
+			}
+
+			if got := ui.ErrorWriter.String(); !strings.Contains(got, "The --verify option cannot be used with") {
+				t.Errorf("Run() with args = %#v outputs %s, but want an error of incompatible options", tc.args, got)
+			}
+		})
+	}
+}
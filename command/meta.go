@@ -1,11 +1,17 @@
 package command
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/minamijoyo/tfupdate/release"
 	"github.com/minamijoyo/tfupdate/tfregistry"
+	"github.com/minamijoyo/tfupdate/tfupdate"
 	"github.com/mitchellh/cli"
 	"github.com/spf13/afero"
 )
@@ -54,3 +60,170 @@ func newRelease(sourceType string, source string) (release.Release, error) {
 		return nil, fmt.Errorf("failed to new release data source. unknown type: %s", sourceType)
 	}
 }
+
+// updateFs returns a filesystem for updating files.
+// If check is true, it returns a copy-on-write filesystem, which keeps
+// updated files only in memory, so that the original files are never
+// modified.
+func (m *Meta) updateFs(check bool) afero.Fs {
+	if check {
+		return afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(m.Fs), afero.NewMemMapFs())
+	}
+	return m.Fs
+}
+
+// validateOutputOptions validates options for outputting results of updates.
+func validateOutputOptions(diff bool, format string) error {
+	switch format {
+	case "text":
+		return nil
+	case "json":
+		if diff {
+			return fmt.Errorf("the --diff option cannot be used with --format=json")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown output format: %s. Valid values are \"text\" or \"json\"", format)
+	}
+}
+
+// validateJobsOption validates the --jobs option.
+func validateJobsOption(jobs int) error {
+	if jobs < 1 {
+		return fmt.Errorf("the --jobs option should be a positive integer, but got %d", jobs)
+	}
+	return nil
+}
+
+// validateBumpOption validates the --bump option.
+// The bump option selects a version from releases, so it cannot be used with
+// an explicit version.
+func validateBumpOption(bump string, version string) error {
+	switch bump {
+	case "":
+		return nil
+	case "patch", "minor", "major":
+		if version != "latest" {
+			return fmt.Errorf("the --bump option cannot be used with --version")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown bump: %s. Valid values are \"patch\", \"minor\" or \"major\"", bump)
+	}
+}
+
+// latestRelease returns the latest release and a version policy.
+// If bump is set, the version policy contains all releases to select a new
+// version from the current one found in each file.
+func latestRelease(ctx context.Context, r release.Release, bump string) (string, tfupdate.VersionPolicy, error) {
+	if bump == "" {
+		v, err := release.Latest(ctx, r)
+		return v, tfupdate.VersionPolicy{}, err
+	}
+
+	releases, err := release.List(ctx, r, math.MaxInt, false)
+	if err != nil {
+		return "", tfupdate.VersionPolicy{}, err
+	}
+
+	if len(releases) == 0 {
+		return "", tfupdate.VersionPolicy{}, fmt.Errorf("no releases found")
+	}
+
+	policy := tfupdate.VersionPolicy{
+		Bump:     bump,
+		Releases: releases,
+	}
+	return releases[len(releases)-1], policy, nil
+}
+
+// jsonReport is a JSON representation of results of updates.
+type jsonReport struct {
+	// Changes is a list of attributes rewritten in all files.
+	Changes []tfupdate.Change `json:"changes"`
+
+	// Failures is a list of files which failed to update.
+	// It is only reported with the --keep-going option.
+	Failures []jsonFailure `json:"failures,omitempty"`
+}
+
+// jsonFailure is a JSON representation of a file which failed to update.
+type jsonFailure struct {
+	// Filename is a path of the file.
+	Filename string `json:"filename"`
+
+	// Error is an error message.
+	Error string `json:"error"`
+}
+
+// marshalJSON encodes a given value as indented JSON.
+// Unlike json.MarshalIndent, it doesn't escape characters such as < and > to
+// keep version constraints readable.
+func marshalJSON(v any) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// outputResults outputs the results of updates and returns an exit status.
+// If format is json, it outputs a list of changes as JSON.
+// If diff is true, it shows a unified diff for each updated file.
+// If check is true, it returns 2 if any files need to be updated, and lists
+// the files unless the diff or JSON is shown.
+// If any files failed to update with the --keep-going option, it outputs a
+// summary of failures and returns 1.
+func (m *Meta) outputResults(gc *tfupdate.GlobalContext, check bool, diff bool, format string) int {
+	results := gc.Results()
+	failures := gc.Failures()
+
+	// Warnings are written to stderr so as not to break the JSON output.
+	for _, w := range gc.Warnings() {
+		m.UI.Warn(w)
+	}
+
+	if format == "json" {
+		report := jsonReport{
+			Changes: []tfupdate.Change{},
+		}
+		for _, r := range results {
+			report.Changes = append(report.Changes, r.Changes...)
+		}
+		for _, f := range failures {
+			report.Failures = append(report.Failures, jsonFailure{Filename: f.Filename, Error: f.Err.Error()})
+		}
+
+		out, err := marshalJSON(report)
+		if err != nil {
+			m.UI.Error(fmt.Sprintf("failed to encode results as JSON: %s", err))
+			return 1
+		}
+		m.UI.Output(out)
+	} else {
+		for _, r := range results {
+			if diff {
+				m.UI.Output(strings.TrimSuffix(tfupdate.Diff(r.Filename, r.Original, r.Updated), "\n"))
+			} else if check {
+				m.UI.Output(r.Filename)
+			}
+		}
+	}
+
+	if len(failures) != 0 {
+		m.UI.Error(fmt.Sprintf("failed to update %d file(s):", len(failures)))
+		for _, f := range failures {
+			m.UI.Error(fmt.Sprintf("  %s: %s", f.Filename, f.Err))
+		}
+		return 1
+	}
+
+	if check && len(results) != 0 {
+		return 2
+	}
+
+	return 0
+}
@@ -0,0 +1,34 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/spf13/afero"
+)
+
+func TestOutputResultsJSON(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	src := `
+terraform {
+  required_version = "~> 1.4.0"
+}
+`
+	if err := afero.WriteFile(fs, "main.tf", []byte(src), 0644); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+
+	ui := cli.NewMockUi()
+	c := &TerraformCommand{Meta: Meta{UI: ui, Fs: fs}}
+	if code := c.Run([]string{"-v", ">= 1.5, < 2.0", "--format", "json", "main.tf"}); code != 0 {
+		t.Fatalf("Run() returns %d: %s", code, ui.ErrorWriter.String())
+	}
+
+	got := ui.OutputWriter.String()
+	for _, want := range []string{`"old_value": "~> 1.4.0"`, `"new_value": ">= 1.5, < 2.0"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Run() outputs %s, but want to contain %s", got, want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
 
 	"github.com/minamijoyo/tfupdate/tfregistry"
@@ -14,20 +15,38 @@ import (
 // ModuleCommand is a command which updates version constraints for module.
 type ModuleCommand struct {
 	Meta
-	name            string
-	version         string
-	path            string
-	recursive       bool
-	ignorePaths     []string
-	sourceMatchType string
+	name              string
+	version           string
+	path              string
+	recursive         bool
+	ignorePaths       []string
+	jobs              int
+	keepGoing         bool
+	sourceMatchType   string
+	bump              string
+	preserveOperators bool
+	allowDowngrade    bool
+	check             bool
+	diff              bool
+	format            string
+	noFormat          bool
 }
 
 // Run runs the procedure of this command.
 func (c *ModuleCommand) Run(args []string) int {
 	cmdFlags := flag.NewFlagSet("module", flag.ContinueOnError)
-	cmdFlags.StringVarP(&c.version, "version", "v", "", "A new version constraint")
+	cmdFlags.StringVarP(&c.version, "version", "v", "latest", "A new version constraint")
 	cmdFlags.BoolVarP(&c.recursive, "recursive", "r", false, "Check a directory recursively")
 	cmdFlags.StringArrayVarP(&c.ignorePaths, "ignore-path", "i", []string{}, "A regular expression for path to ignore")
+	cmdFlags.IntVarP(&c.jobs, "jobs", "j", 1, "A number of modules processed concurrently in a recursive mode")
+	cmdFlags.BoolVar(&c.keepGoing, "keep-going", false, "Continue updating other files when a file fails to update")
+	cmdFlags.StringVar(&c.bump, "bump", "", "A level of version bump. Valid values are \"patch\", \"minor\" or \"major\"")
+	cmdFlags.BoolVar(&c.preserveOperators, "preserve-operators", false, "Rewrite only version operands and keep operators in the current constraint")
+	cmdFlags.BoolVar(&c.allowDowngrade, "allow-downgrade", false, "Allow updating to a lower version than the current one")
+	cmdFlags.BoolVar(&c.check, "check", false, "Check whether updates are pending without writing files")
+	cmdFlags.BoolVar(&c.diff, "diff", false, "Show a unified diff of updated files")
+	cmdFlags.StringVar(&c.format, "format", "text", "An output format. Valid values are \"text\" or \"json\"")
+	cmdFlags.BoolVar(&c.noFormat, "no-format", false, "Rewrite only changed values without formatting files")
 	cmdFlags.StringVar(&c.sourceMatchType, "source-match-type", "full", "Define how to match module source URLs. Valid values are \"full\" or \"regex\".")
 
 	if err := cmdFlags.Parse(args); err != nil {
@@ -35,6 +54,21 @@ func (c *ModuleCommand) Run(args []string) int {
 		return 1
 	}
 
+	if err := validateJobsOption(c.jobs); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := validateOutputOptions(c.diff, c.format); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := validateBumpOption(c.bump, c.version); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	if len(cmdFlags.Args()) != 2 {
 		c.UI.Error(fmt.Sprintf("The command expects 2 arguments, but got %d", len(cmdFlags.Args())))
 		c.UI.Error(c.Help())
@@ -45,21 +79,43 @@ func (c *ModuleCommand) Run(args []string) int {
 	c.path = cmdFlags.Arg(1)
 
 	v := c.version
-	if len(v) == 0 {
-		// For modules, automatic latest version resolution is not simple.
-		// To implement, we will probably need to get information from the Terraform Registry.
-		c.UI.Error("A new version constraint is required. Automatic latest version resolution is not currently supported for modules.")
-		return 1
+	policy := tfupdate.VersionPolicy{}
+	if v == "latest" {
+		if c.sourceMatchType == "regex" {
+			c.UI.Error("A new version constraint is required. Automatic latest version resolution is not supported with --source-match-type=regex.")
+			return 1
+		}
+
+		sourceType, source, err := detectModuleReleaseSource(c.name)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+
+		r, err := newRelease(sourceType, source)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+
+		v, policy, err = latestRelease(context.Background(), r, c.bump)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
 	}
 
+	policy.PreserveOperators = c.preserveOperators
+	policy.AllowDowngrade = c.allowDowngrade
+
 	log.Printf("[INFO] Update module %s to %s", c.name, v)
-	option, err := tfupdate.NewOption("module", c.name, v, []string{}, c.recursive, c.ignorePaths, c.sourceMatchType, tfregistry.Config{})
+	option, err := tfupdate.NewOption("module", c.name, v, []string{}, tfregistry.Config{}, tfupdate.UpdatePolicy{SourceMatchType: c.sourceMatchType, NoFormat: c.noFormat}, policy, tfupdate.LockPolicy{}, tfupdate.WalkPolicy{Recursive: c.recursive, IgnorePaths: c.ignorePaths, Jobs: c.jobs, KeepGoing: c.keepGoing})
 	if err != nil {
 		c.UI.Error(err.Error())
 		return 1
 	}
 
-	gc, err := tfupdate.NewGlobalContext(c.Fs, option)
+	gc, err := tfupdate.NewGlobalContext(c.updateFs(c.check), option)
 	if err != nil {
 		c.UI.Error(err.Error())
 		return 1
@@ -71,7 +127,61 @@ func (c *ModuleCommand) Run(args []string) int {
 		return 1
 	}
 
-	return 0
+	return c.outputResults(gc, c.check, c.diff, c.format)
+}
+
+// moduleRegistrySourceRegexp is a regular expression for a module source
+// address in the Terraform Registry: [HOSTNAME/]NAMESPACE/NAME/PROVIDER.
+var moduleRegistrySourceRegexp = regexp.MustCompile(`^([0-9A-Za-z.-]+\.[0-9A-Za-z.-]+/)?[0-9A-Za-z_-]+/[0-9A-Za-z_-]+/[0-9A-Za-z_-]+$`)
+
+// detectModuleReleaseSource detects a type and a path of release data source
+// from a given module source address without a version reference.
+// It returns a pair of sourceType and source which can be passed to newRelease.
+func detectModuleReleaseSource(name string) (string, string, error) {
+	if moduleRegistrySourceRegexp.MatchString(name) {
+		return "tfregistryModule", name, nil
+	}
+
+	// Parse a git repository URL.
+	// e.g.
+	//   git::https://github.com/owner/repo.git//subdir
+	//   git::ssh://git@github.com/owner/repo.git
+	//   git@github.com:owner/repo.git
+	//   github.com/owner/repo
+	addr := strings.TrimPrefix(name, "git::")
+	var host, path string
+	if before, after, ok := strings.Cut(addr, "://"); ok && (before == "https" || before == "ssh") {
+		rest := after
+		if _, r, found := strings.Cut(rest, "@"); found {
+			rest = r
+		}
+		host, path, _ = strings.Cut(rest, "/")
+	} else if strings.HasPrefix(addr, "git@") {
+		host, path, _ = strings.Cut(strings.TrimPrefix(addr, "git@"), ":")
+	} else {
+		host, path, _ = strings.Cut(addr, "/")
+	}
+
+	// Remove a query string, a subdirectory and a .git suffix.
+	path, _, _ = strings.Cut(path, "?")
+	path, _, _ = strings.Cut(path, "//")
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+
+	switch host {
+	case "github.com":
+		s := strings.Split(path, "/")
+		if len(s) < 2 {
+			break
+		}
+		return "github", s[0] + "/" + s[1], nil
+	case "gitlab.com":
+		if !strings.Contains(path, "/") {
+			break
+		}
+		return "gitlab", path, nil
+	}
+
+	return "", "", fmt.Errorf("failed to detect a release data source for module: %s. Automatic latest version resolution is not supported for this module source. Please specify the version explicitly", name)
 }
 
 // Help returns long-form help text.
@@ -88,12 +198,38 @@ Arguments
   PATH               A path of file or directory to update
 
 Options:
-  -v  --version       A new version constraint (required)
-                      Automatic latest version resolution is not currently supported for modules.
+  -v  --version       A new version constraint (default: latest)
+                      If the version is omitted, the latest version is automatically checked and set.
+                      Automatic latest version resolution supports the following module sources:
+                        - Terraform Registry (e.g. terraform-aws-modules/vpc/aws)
+                        - GitHub (e.g. git::https://github.com/terraform-aws-modules/terraform-aws-vpc.git)
+                        - GitLab (e.g. git::https://gitlab.com/example/vpc.git)
   -r  --recursive     Check a directory recursively (default: false)
   -i  --ignore-path   A regular expression for path to ignore
                       If you want to ignore multiple directories, set the flag multiple times.
+  -j  --jobs          A number of modules processed concurrently in a recursive mode (default: 1)
+                      If greater than 1, an error in a module doesn't stop processing the others,
+                      and all errors are reported at the end.
+      --keep-going    Continue updating other files when a file fails to update (default: false)
+                      Failed files are reported at the end, and the command exits with status 1 if any.
   --source-match-type Define how to match MODULE_NAME to the module source URLs. Valid values are "full" or "regex". (default: full)
+  --bump              A level of version bump. Valid values are patch, minor or major
+                      The newest release within the current minor version (patch), major version (minor)
+                      or any version (major) is selected for each file. It never downgrades.
+                      It cannot be used with --version.
+  --preserve-operators
+                      Rewrite only version operands and keep operators in the current constraint (default: false)
+                      For example, "~> 4.0" becomes "~> 5.1" and ">= 1.5, < 2.0" becomes ">= 2.3, < 3.0".
+  --allow-downgrade   Allow updating to a lower version than the current one (default: false)
+                      By default, such updates are skipped with a warning.
+  --check             Check whether updates are pending without writing files (default: false)
+                      List files to be updated and exit with status 2 if any.
+  --diff              Show a unified diff of updated files (default: false)
+                      Use with --check to preview changes without writing files.
+  --format            An output format. Valid values are "text" or "json" (default: text)
+                      The json format outputs a list of changed attributes in all files.
+  --no-format         Rewrite only changed values without formatting files (default: false)
+                      By default, updated files are formatted as terraform fmt does.
 `
 	return strings.TrimSpace(helpText)
 }
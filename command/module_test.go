@@ -0,0 +1,386 @@
+package command
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfupdate/tfupdate"
+	"github.com/mitchellh/cli"
+)
+
+func TestDetectModuleReleaseSource(t *testing.T) {
+	cases := []struct {
+		name       string
+		sourceType string
+		source     string
+		ok         bool
+	}{
+		{
+			name:       "terraform-aws-modules/vpc/aws",
+			sourceType: "tfregistryModule",
+			source:     "terraform-aws-modules/vpc/aws",
+			ok:         true,
+		},
+		{
+			name:       "app.terraform.io/example-corp/k8s-cluster/azurerm",
+			sourceType: "tfregistryModule",
+			source:     "app.terraform.io/example-corp/k8s-cluster/azurerm",
+			ok:         true,
+		},
+		{
+			name:       "git::https://github.com/terraform-aws-modules/terraform-aws-vpc.git",
+			sourceType: "github",
+			source:     "terraform-aws-modules/terraform-aws-vpc",
+			ok:         true,
+		},
+		{
+			name:       "git::https://github.com/terraform-aws-modules/terraform-aws-vpc.git//modules/vpc-endpoints",
+			sourceType: "github",
+			source:     "terraform-aws-modules/terraform-aws-vpc",
+			ok:         true,
+		},
+		{
+			name:       "git::https://github.com/terraform-aws-modules/terraform-aws-vpc.git?ref=v5.0.0",
+			sourceType: "github",
+			source:     "terraform-aws-modules/terraform-aws-vpc",
+			ok:         true,
+		},
+		{
+			name:       "git::https://github.com/terraform-aws-modules/terraform-aws-vpc.git//modules/vpc-endpoints?ref=v5.0.0",
+			sourceType: "github",
+			source:     "terraform-aws-modules/terraform-aws-vpc",
+			ok:         true,
+		},
+		{
+			name:       "git::ssh://git@github.com/terraform-aws-modules/terraform-aws-vpc.git",
+			sourceType: "github",
+			source:     "terraform-aws-modules/terraform-aws-vpc",
+			ok:         true,
+		},
+		{
+			name:       "git@github.com:terraform-aws-modules/terraform-aws-vpc.git",
+			sourceType: "github",
+			source:     "terraform-aws-modules/terraform-aws-vpc",
+			ok:         true,
+		},
+		{
+			name:       "git::git@github.com:terraform-aws-modules/terraform-aws-vpc.git?ref=v5.0.0",
+			sourceType: "github",
+			source:     "terraform-aws-modules/terraform-aws-vpc",
+			ok:         true,
+		},
+		{
+			name:       "github.com/terraform-aws-modules/terraform-aws-vpc",
+			sourceType: "github",
+			source:     "terraform-aws-modules/terraform-aws-vpc",
+			ok:         true,
+		},
+		{
+			name:       "github.com/terraform-aws-modules/terraform-aws-vpc//modules/vpc-endpoints?ref=v5.0.0",
+			sourceType: "github",
+			source:     "terraform-aws-modules/terraform-aws-vpc",
+			ok:         true,
+		},
+		{
+			name:       "git::https://gitlab.com/example/group/vpc.git?ref=v1.0.0",
+			sourceType: "gitlab",
+			source:     "example/group/vpc",
+			ok:         true,
+		},
+		{
+			name:       "git::https://example.com/vpc.git",
+			sourceType: "",
+			source:     "",
+			ok:         false,
+		},
+		{
+			name:       "github.com/terraform-aws-modules",
+			sourceType: "",
+			source:     "",
+			ok:         false,
+		},
+		{
+			name:       "./modules/vpc",
+			sourceType: "",
+			source:     "",
+			ok:         false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sourceType, source, err := detectModuleReleaseSource(tc.name)
+			if tc.ok && err != nil {
+				t.Fatalf("detectModuleReleaseSource() with name = %s returns unexpected err: %s", tc.name, err)
+			}
+
+			if !tc.ok && err == nil {
+				t.Fatalf("detectModuleReleaseSource() with name = %s expects to return an error, but no error: sourceType = %s, source = %s", tc.name, sourceType, source)
+			}
+
+			if sourceType != tc.sourceType || source != tc.source {
+				t.Errorf("detectModuleReleaseSource() with name = %s returns (%s, %s), but want = (%s, %s)", tc.name, sourceType, source, tc.sourceType, tc.source)
+			}
+		})
+	}
+}
+
+func TestProviderReleaseSource(t *testing.T) {
+	cases := []struct {
+		sourceType string
+		address    string
+		source     string
+		ok         bool
+	}{
+		{
+			sourceType: "tfregistryProvider",
+			address:    "hashicorp/aws",
+			source:     "hashicorp/aws",
+			ok:         true,
+		},
+		{
+			sourceType: "tfregistryProvider",
+			address:    "registry.opentofu.org/hashicorp/aws",
+			source:     "registry.opentofu.org/hashicorp/aws",
+			ok:         true,
+		},
+		{
+			sourceType: "github",
+			address:    "integrations/github",
+			source:     "integrations/terraform-provider-github",
+			ok:         true,
+		},
+		{
+			sourceType: "github",
+			address:    "registry.terraform.io/integrations/github",
+			source:     "integrations/terraform-provider-github",
+			ok:         true,
+		},
+		{
+			sourceType: "gitlab",
+			address:    "example/foo",
+			source:     "example/terraform-provider-foo",
+			ok:         true,
+		},
+		{
+			sourceType: "gitlab",
+			address:    "gitlab.example.com/example/foo",
+			source:     "example/terraform-provider-foo",
+			ok:         true,
+		},
+		{
+			sourceType: "tfregistryModule",
+			address:    "hashicorp/aws",
+			source:     "",
+			ok:         false,
+		},
+		{
+			sourceType: "",
+			address:    "hashicorp/aws",
+			source:     "",
+			ok:         false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.sourceType+":"+tc.address, func(t *testing.T) {
+			source, err := providerReleaseSource(tc.sourceType, tc.address)
+			if tc.ok && err != nil {
+				t.Fatalf("providerReleaseSource() with sourceType = %s, address = %s returns unexpected err: %s", tc.sourceType, tc.address, err)
+			}
+
+			if !tc.ok && err == nil {
+				t.Fatalf("providerReleaseSource() with sourceType = %s, address = %s expects to return an error, but no error: source = %s", tc.sourceType, tc.address, source)
+			}
+
+			if source != tc.source {
+				t.Errorf("providerReleaseSource() with sourceType = %s, address = %s returns %s, but want = %s", tc.sourceType, tc.address, source, tc.source)
+			}
+		})
+	}
+}
+
+func TestDependencyReleaseSource(t *testing.T) {
+	cases := []struct {
+		desc       string
+		dep        tfupdate.Dependency
+		sourceType string
+		source     string
+		ok         bool
+	}{
+		{
+			desc:       "terraform",
+			dep:        tfupdate.Dependency{Type: "terraform", Version: "1.5.0"},
+			sourceType: "github",
+			source:     "hashicorp/terraform",
+			ok:         true,
+		},
+		{
+			desc:       "opentofu",
+			dep:        tfupdate.Dependency{Type: "opentofu", Version: "1.6.0"},
+			sourceType: "github",
+			source:     "opentofu/opentofu",
+			ok:         true,
+		},
+		{
+			desc:       "provider with source",
+			dep:        tfupdate.Dependency{Type: "provider", Name: "github", Source: "integrations/github", Version: "5.0.0"},
+			sourceType: "tfregistryProvider",
+			source:     "integrations/github",
+			ok:         true,
+		},
+		{
+			desc:       "provider without source",
+			dep:        tfupdate.Dependency{Type: "provider", Name: "aws", Version: "5.0.0"},
+			sourceType: "tfregistryProvider",
+			source:     "hashicorp/aws",
+			ok:         true,
+		},
+		{
+			desc:       "registry module",
+			dep:        tfupdate.Dependency{Type: "module", Name: "vpc", Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0"},
+			sourceType: "tfregistryModule",
+			source:     "terraform-aws-modules/vpc/aws",
+			ok:         true,
+		},
+		{
+			desc:       "git module",
+			dep:        tfupdate.Dependency{Type: "module", Name: "vpc", Source: "git::https://github.com/terraform-aws-modules/terraform-aws-vpc.git", Ref: "v5.0.0"},
+			sourceType: "github",
+			source:     "terraform-aws-modules/terraform-aws-vpc",
+			ok:         true,
+		},
+		{
+			desc:       "local module",
+			dep:        tfupdate.Dependency{Type: "module", Name: "vpc", Source: "./modules/vpc"},
+			sourceType: "",
+			source:     "",
+			ok:         false,
+		},
+		{
+			desc:       "unknown type",
+			dep:        tfupdate.Dependency{Type: "foo"},
+			sourceType: "",
+			source:     "",
+			ok:         false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			sourceType, source, err := dependencyReleaseSource(tc.dep)
+			if tc.ok && err != nil {
+				t.Fatalf("dependencyReleaseSource() with dep = %#v returns unexpected err: %s", tc.dep, err)
+			}
+
+			if !tc.ok && err == nil {
+				t.Fatalf("dependencyReleaseSource() with dep = %#v expects to return an error, but no error: sourceType = %s, source = %s", tc.dep, sourceType, source)
+			}
+
+			if sourceType != tc.sourceType || source != tc.source {
+				t.Errorf("dependencyReleaseSource() with dep = %#v returns (%s, %s), but want = (%s, %s)", tc.dep, sourceType, source, tc.sourceType, tc.source)
+			}
+		})
+	}
+}
+
+func TestOutdatedCommandFindOutdated(t *testing.T) {
+	latests := map[string]string{
+		"github:hashicorp/terraform":                     "1.9.8",
+		"tfregistryProvider:hashicorp/aws":               "5.74.0",
+		"tfregistryModule:terraform-aws-modules/vpc/aws": "5.1.2",
+	}
+
+	cases := []struct {
+		desc     string
+		deps     []tfupdate.Dependency
+		want     []outdatedDependency
+		calls    []string
+		warnings string
+	}{
+		{
+			desc: "outdated",
+			deps: []tfupdate.Dependency{
+				{Filename: "main.tf", Type: "terraform", Version: "1.5.0"},
+				{Filename: "main.tf", Type: "provider", Name: "aws", Source: "hashicorp/aws", Version: "~> 4.0", Syntax: "object"},
+				{Filename: "main.tf", Type: "provider", Name: "aws", Version: "4.0.0", Syntax: "legacy_provider_block"},
+				{Filename: "modules/vpc.tf", Type: "module", Name: "vpc", Source: "terraform-aws-modules/vpc/aws", Version: "5.1.0"},
+			},
+			want: []outdatedDependency{
+				{location: "main.tf", depType: "terraform", name: "terraform", current: "1.5.0", latest: "1.9.8", behind: "minor"},
+				{location: "main.tf:required_providers.aws", depType: "provider", name: "aws", current: "~> 4.0", latest: "5.74.0", behind: "major"},
+				{location: "main.tf:provider.aws", depType: "provider", name: "aws", current: "4.0.0", latest: "5.74.0", behind: "major"},
+				{location: "modules/vpc.tf:module.vpc", depType: "module", name: "vpc", current: "5.1.0", latest: "5.1.2", behind: "patch"},
+			},
+			calls: []string{
+				"github:hashicorp/terraform",
+				"tfregistryProvider:hashicorp/aws",
+				"tfregistryModule:terraform-aws-modules/vpc/aws",
+			},
+		},
+		{
+			desc: "up to date",
+			deps: []tfupdate.Dependency{
+				{Filename: "main.tf", Type: "terraform", Version: "~> 1.9"},
+			},
+			want:  []outdatedDependency{},
+			calls: []string{"github:hashicorp/terraform"},
+		},
+		{
+			desc: "skip without a version or a release source",
+			deps: []tfupdate.Dependency{
+				{Filename: "main.tf", Type: "provider", Name: "aws", Source: "hashicorp/aws", Syntax: "object"},
+				{Filename: "main.tf", Type: "module", Name: "local", Source: "./modules/local", Version: "1.0.0"},
+			},
+			want:  []outdatedDependency{},
+			calls: []string{},
+		},
+		{
+			desc: "skip failed sources without retrying",
+			deps: []tfupdate.Dependency{
+				{Filename: "a.tf", Type: "provider", Name: "foo", Source: "example/foo", Version: "1.0.0", Syntax: "object"},
+				{Filename: "b.tf", Type: "provider", Name: "foo", Source: "example/foo", Version: "1.0.0", Syntax: "object"},
+				{Filename: "c.tf", Type: "terraform", Version: "1.5.0"},
+			},
+			want: []outdatedDependency{
+				{location: "c.tf", depType: "terraform", name: "terraform", current: "1.5.0", latest: "1.9.8", behind: "minor"},
+			},
+			calls: []string{
+				"tfregistryProvider:example/foo",
+				"github:hashicorp/terraform",
+			},
+			warnings: "failed to get the latest version of example/foo: not found\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := &OutdatedCommand{Meta: Meta{UI: ui}}
+			calls := []string{}
+			latestFn := func(sourceType string, source string) (string, error) {
+				key := sourceType + ":" + source
+				calls = append(calls, key)
+				latest, ok := latests[key]
+				if !ok {
+					return "", fmt.Errorf("not found")
+				}
+				return latest, nil
+			}
+
+			got := c.findOutdated(tc.deps, latestFn)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("findOutdated() returns %#v, but want = %#v", got, tc.want)
+			}
+
+			if !reflect.DeepEqual(calls, tc.calls) {
+				t.Errorf("findOutdated() calls latestFn with %#v, but want = %#v", calls, tc.calls)
+			}
+
+			if got := ui.ErrorWriter.String(); got != tc.warnings {
+				t.Errorf("findOutdated() warns %q, but want = %q", got, tc.warnings)
+			}
+		})
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"log"
 	"strings"
 
-	"github.com/minamijoyo/tfupdate/release"
 	"github.com/minamijoyo/tfupdate/tfregistry"
 	"github.com/minamijoyo/tfupdate/tfupdate"
 	flag "github.com/spf13/pflag"
@@ -15,10 +14,19 @@ import (
 // OpenTofuCommand is a command which updates version constraints for OpenTofu.
 type OpenTofuCommand struct {
 	Meta
-	version     string
-	path        string
-	recursive   bool
-	ignorePaths []string
+	version           string
+	path              string
+	recursive         bool
+	ignorePaths       []string
+	jobs              int
+	keepGoing         bool
+	bump              string
+	preserveOperators bool
+	allowDowngrade    bool
+	check             bool
+	diff              bool
+	format            string
+	noFormat          bool
 }
 
 // Run runs the procedure of this command.
@@ -27,12 +35,36 @@ func (c *OpenTofuCommand) Run(args []string) int {
 	cmdFlags.StringVarP(&c.version, "version", "v", "latest", "A new version constraint")
 	cmdFlags.BoolVarP(&c.recursive, "recursive", "r", false, "Check a directory recursively")
 	cmdFlags.StringArrayVarP(&c.ignorePaths, "ignore-path", "i", []string{}, "A regular expression for path to ignore")
+	cmdFlags.IntVarP(&c.jobs, "jobs", "j", 1, "A number of modules processed concurrently in a recursive mode")
+	cmdFlags.BoolVar(&c.keepGoing, "keep-going", false, "Continue updating other files when a file fails to update")
+	cmdFlags.StringVar(&c.bump, "bump", "", "A level of version bump. Valid values are \"patch\", \"minor\" or \"major\"")
+	cmdFlags.BoolVar(&c.preserveOperators, "preserve-operators", false, "Rewrite only version operands and keep operators in the current constraint")
+	cmdFlags.BoolVar(&c.allowDowngrade, "allow-downgrade", false, "Allow updating to a lower version than the current one")
+	cmdFlags.BoolVar(&c.check, "check", false, "Check whether updates are pending without writing files")
+	cmdFlags.BoolVar(&c.diff, "diff", false, "Show a unified diff of updated files")
+	cmdFlags.StringVar(&c.format, "format", "text", "An output format. Valid values are \"text\" or \"json\"")
+	cmdFlags.BoolVar(&c.noFormat, "no-format", false, "Rewrite only changed values without formatting files")
 
 	if err := cmdFlags.Parse(args); err != nil {
 		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
 		return 1
 	}
 
+	if err := validateJobsOption(c.jobs); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := validateOutputOptions(c.diff, c.format); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := validateBumpOption(c.bump, c.version); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	if len(cmdFlags.Args()) != 1 {
 		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
 		c.UI.Error(c.Help())
@@ -42,6 +74,7 @@ func (c *OpenTofuCommand) Run(args []string) int {
 	c.path = cmdFlags.Arg(0)
 
 	v := c.version
+	policy := tfupdate.VersionPolicy{}
 	if v == "latest" {
 		r, err := newRelease("github", "opentofu/opentofu")
 		if err != nil {
@@ -49,21 +82,24 @@ func (c *OpenTofuCommand) Run(args []string) int {
 			return 1
 		}
 
-		v, err = release.Latest(context.Background(), r)
+		v, policy, err = latestRelease(context.Background(), r, c.bump)
 		if err != nil {
 			c.UI.Error(err.Error())
 			return 1
 		}
 	}
 
+	policy.PreserveOperators = c.preserveOperators
+	policy.AllowDowngrade = c.allowDowngrade
+
 	log.Printf("[INFO] Update opentofu to %s", v)
-	option, err := tfupdate.NewOption("opentofu", "", v, []string{}, c.recursive, c.ignorePaths, "", tfregistry.Config{})
+	option, err := tfupdate.NewOption("opentofu", "", v, []string{}, tfregistry.Config{}, tfupdate.UpdatePolicy{NoFormat: c.noFormat}, policy, tfupdate.LockPolicy{}, tfupdate.WalkPolicy{Recursive: c.recursive, IgnorePaths: c.ignorePaths, Jobs: c.jobs, KeepGoing: c.keepGoing})
 	if err != nil {
 		c.UI.Error(err.Error())
 		return 1
 	}
 
-	gc, err := tfupdate.NewGlobalContext(c.Fs, option)
+	gc, err := tfupdate.NewGlobalContext(c.updateFs(c.check), option)
 	if err != nil {
 		c.UI.Error(err.Error())
 		return 1
@@ -75,7 +111,7 @@ func (c *OpenTofuCommand) Run(args []string) int {
 		return 1
 	}
 
-	return 0
+	return c.outputResults(gc, c.check, c.diff, c.format)
 }
 
 // Help returns long-form help text.
@@ -85,6 +121,8 @@ Usage: tfupdate opentofu [options] <PATH>
 
 Arguments
   PATH               A path of file or directory to update
+                     Version files for version managers (.opentofu-version and .tool-versions)
+                     are also updated to the same version.
 
 Options:
   -v  --version      A new version constraint (default: latest)
@@ -92,6 +130,29 @@ Options:
   -r  --recursive    Check a directory recursively (default: false)
   -i  --ignore-path  A regular expression for path to ignore
                      If you want to ignore multiple directories, set the flag multiple times.
+  -j  --jobs         A number of modules processed concurrently in a recursive mode (default: 1)
+                     If greater than 1, an error in a module doesn't stop processing the others,
+                     and all errors are reported at the end.
+      --keep-going   Continue updating other files when a file fails to update (default: false)
+                     Failed files are reported at the end, and the command exits with status 1 if any.
+      --bump         A level of version bump. Valid values are patch, minor or major
+                     The newest release within the current minor version (patch), major version (minor)
+                     or any version (major) is selected for each file. It never downgrades.
+                     It cannot be used with --version.
+      --preserve-operators
+                     Rewrite only version operands and keep operators in the current constraint (default: false)
+                     For example, "~> 4.0" becomes "~> 5.1" and ">= 1.5, < 2.0" becomes ">= 2.3, < 3.0".
+      --allow-downgrade
+                     Allow updating to a lower version than the current one (default: false)
+                     By default, such updates are skipped with a warning.
+      --check        Check whether updates are pending without writing files (default: false)
+                     List files to be updated and exit with status 2 if any.
+      --diff         Show a unified diff of updated files (default: false)
+                     Use with --check to preview changes without writing files.
+      --format       An output format. Valid values are "text" or "json" (default: text)
+                     The json format outputs a list of changed attributes in all files.
+      --no-format    Rewrite only changed values without formatting files (default: false)
+                     By default, updated files are formatted as terraform fmt does.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -0,0 +1,235 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/minamijoyo/tfupdate/release"
+	"github.com/minamijoyo/tfupdate/tfregistry"
+	"github.com/minamijoyo/tfupdate/tfupdate"
+	flag "github.com/spf13/pflag"
+)
+
+// OutdatedCommand is a command which lists outdated dependencies.
+type OutdatedCommand struct {
+	Meta
+	path        string
+	recursive   bool
+	ignorePaths []string
+}
+
+// Run runs the procedure of this command.
+func (c *OutdatedCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("outdated", flag.ContinueOnError)
+	cmdFlags.BoolVarP(&c.recursive, "recursive", "r", false, "Check a directory recursively")
+	cmdFlags.StringArrayVarP(&c.ignorePaths, "ignore-path", "i", []string{}, "A regular expression for path to ignore")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	c.path = cmdFlags.Arg(0)
+
+	option, err := tfupdate.NewOption("", "", "", []string{}, tfregistry.Config{}, tfupdate.UpdatePolicy{}, tfupdate.VersionPolicy{}, tfupdate.LockPolicy{}, tfupdate.WalkPolicy{Recursive: c.recursive, IgnorePaths: c.ignorePaths})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	deps, err := tfupdate.CollectDependencies(c.Fs, option, c.path)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	outdated := c.findOutdated(deps, latestVersion)
+	if len(outdated) == 0 {
+		return 0
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LOCATION\tTYPE\tNAME\tCURRENT\tLATEST\tBEHIND")
+	for _, o := range outdated {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", o.location, o.depType, o.name, o.current, o.latest, o.behind)
+	}
+
+	if err := w.Flush(); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to write output: %s", err))
+		return 1
+	}
+	c.UI.Output(strings.TrimSuffix(sb.String(), "\n"))
+
+	return 0
+}
+
+// outdatedDependency is a row of the outdated command output.
+type outdatedDependency struct {
+	location string
+	depType  string
+	name     string
+	current  string
+	latest   string
+	behind   string
+}
+
+// findOutdated returns dependencies which don't accept the latest version.
+// The latestFn is called at most once for each release data source.
+// Dependencies without a version or a known release data source are skipped.
+func (c *OutdatedCommand) findOutdated(deps []tfupdate.Dependency, latestFn func(sourceType string, source string) (string, error)) []outdatedDependency {
+	// Cache the latest versions to avoid fetching the same source repeatedly.
+	latests := make(map[string]string)
+	failed := make(map[string]bool)
+
+	outdated := []outdatedDependency{}
+	for _, d := range deps {
+		current := d.Version
+		if current == "" {
+			// A version reference in the module source.
+			current = d.Ref
+		}
+		if current == "" {
+			continue
+		}
+
+		sourceType, source, err := dependencyReleaseSource(d)
+		if err != nil {
+			log.Printf("[DEBUG] skip dependency: %s", err)
+			continue
+		}
+
+		key := sourceType + ":" + source
+		if failed[key] {
+			continue
+		}
+		latest, ok := latests[key]
+		if !ok {
+			latest, err = latestFn(sourceType, source)
+			if err != nil {
+				c.UI.Warn(fmt.Sprintf("failed to get the latest version of %s: %s", source, err))
+				failed[key] = true
+				continue
+			}
+			latests[key] = latest
+		}
+
+		behind := tfupdate.VersionGap(current, latest)
+		if behind == "" {
+			continue
+		}
+
+		name := d.Name
+		if name == "" {
+			name = d.Type
+		}
+		location := d.Filename
+		if address := dependencyAddress(d); address != "" {
+			location += ":" + address
+		}
+		outdated = append(outdated, outdatedDependency{
+			location: location,
+			depType:  d.Type,
+			name:     name,
+			current:  current,
+			latest:   latest,
+			behind:   behind,
+		})
+	}
+
+	return outdated
+}
+
+// dependencyAddress returns an address of the block where a given dependency
+// is declared, such as module.vpc or required_providers.aws.
+// It returns an empty string if the file has no such a block.
+func dependencyAddress(d tfupdate.Dependency) string {
+	switch d.Type {
+	case "provider":
+		if d.Syntax == "legacy_provider_block" {
+			return "provider." + d.Name
+		}
+		return "required_providers." + d.Name
+	case "module":
+		if d.Name == "" {
+			// The module in terragrunt.hcl doesn't have a name.
+			return ""
+		}
+		return "module." + d.Name
+	default:
+		return ""
+	}
+}
+
+// dependencyReleaseSource returns a type and path of release data source for
+// a given dependency.
+func dependencyReleaseSource(d tfupdate.Dependency) (string, string, error) {
+	switch d.Type {
+	case "terraform":
+		return "github", "hashicorp/terraform", nil
+	case "opentofu":
+		return "github", "opentofu/opentofu", nil
+	case "provider":
+		address := d.Source
+		if address == "" {
+			address = d.Name
+		}
+		// Complement the official hashicorp namespace for a short name.
+		if !strings.Contains(address, "/") {
+			address = "hashicorp/" + address
+		}
+		source, err := providerReleaseSource("tfregistryProvider", address)
+		return "tfregistryProvider", source, err
+	case "module":
+		return detectModuleReleaseSource(d.Source)
+	default:
+		return "", "", fmt.Errorf("unknown dependency type: %s", d.Type)
+	}
+}
+
+// latestVersion returns the latest version from a given release data source.
+func latestVersion(sourceType string, source string) (string, error) {
+	r, err := newRelease(sourceType, source)
+	if err != nil {
+		return "", err
+	}
+
+	return release.Latest(context.Background(), r)
+}
+
+// Help returns long-form help text.
+func (c *OutdatedCommand) Help() string {
+	helpText := `
+Usage: tfupdate outdated [options] <PATH>
+
+Arguments
+  PATH               A path of file or directory to check
+
+Options:
+  -r  --recursive    Check a directory recursively (default: false)
+  -i  --ignore-path  A regular expression for path to ignore
+                     If you want to ignore multiple directories, set the flag multiple times.
+
+List version constraints of terraform, opentofu, providers and modules which
+don't accept the latest version. It never writes any files.
+The LOCATION column shows a filename and an address of the block such as
+module.vpc or required_providers.aws. The BEHIND column shows the most
+significant segment of the version which differs from the latest one:
+major, minor or patch.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *OutdatedCommand) Synopsis() string {
+	return "List outdated dependencies"
+}
@@ -6,7 +6,6 @@ import (
 	"log"
 	"strings"
 
-	"github.com/minamijoyo/tfupdate/release"
 	"github.com/minamijoyo/tfupdate/tfregistry"
 	"github.com/minamijoyo/tfupdate/tfupdate"
 	flag "github.com/spf13/pflag"
@@ -15,11 +14,21 @@ import (
 // ProviderCommand is a command which updates version constraints for provider.
 type ProviderCommand struct {
 	Meta
-	name        string
-	version     string
-	path        string
-	recursive   bool
-	ignorePaths []string
+	name              string
+	version           string
+	path              string
+	recursive         bool
+	ignorePaths       []string
+	jobs              int
+	keepGoing         bool
+	sourceType        string
+	bump              string
+	preserveOperators bool
+	allowDowngrade    bool
+	check             bool
+	diff              bool
+	format            string
+	noFormat          bool
 }
 
 // Run runs the procedure of this command.
@@ -28,12 +37,37 @@ func (c *ProviderCommand) Run(args []string) int {
 	cmdFlags.StringVarP(&c.version, "version", "v", "latest", "A new version constraint")
 	cmdFlags.BoolVarP(&c.recursive, "recursive", "r", false, "Check a directory recursively")
 	cmdFlags.StringArrayVarP(&c.ignorePaths, "ignore-path", "i", []string{}, "A regular expression for path to ignore")
+	cmdFlags.IntVarP(&c.jobs, "jobs", "j", 1, "A number of modules processed concurrently in a recursive mode")
+	cmdFlags.BoolVar(&c.keepGoing, "keep-going", false, "Continue updating other files when a file fails to update")
+	cmdFlags.StringVarP(&c.sourceType, "source-type", "s", "tfregistryProvider", "A type of release data source")
+	cmdFlags.StringVar(&c.bump, "bump", "", "A level of version bump. Valid values are \"patch\", \"minor\" or \"major\"")
+	cmdFlags.BoolVar(&c.preserveOperators, "preserve-operators", false, "Rewrite only version operands and keep operators in the current constraint")
+	cmdFlags.BoolVar(&c.allowDowngrade, "allow-downgrade", false, "Allow updating to a lower version than the current one")
+	cmdFlags.BoolVar(&c.check, "check", false, "Check whether updates are pending without writing files")
+	cmdFlags.BoolVar(&c.diff, "diff", false, "Show a unified diff of updated files")
+	cmdFlags.StringVar(&c.format, "format", "text", "An output format. Valid values are \"text\" or \"json\"")
+	cmdFlags.BoolVar(&c.noFormat, "no-format", false, "Rewrite only changed values without formatting files")
 
 	if err := cmdFlags.Parse(args); err != nil {
 		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
 		return 1
 	}
 
+	if err := validateJobsOption(c.jobs); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := validateOutputOptions(c.diff, c.format); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := validateBumpOption(c.bump, c.version); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	if len(cmdFlags.Args()) != 2 {
 		c.UI.Error(fmt.Sprintf("The command expects 2 arguments, but got %d", len(cmdFlags.Args())))
 		c.UI.Error(c.Help())
@@ -44,35 +78,52 @@ func (c *ProviderCommand) Run(args []string) int {
 	c.path = cmdFlags.Arg(1)
 
 	v := c.version
+	policy := tfupdate.VersionPolicy{}
 	if v == "latest" {
-		source := ""
-		if strings.Contains(c.name, "/") {
-			namespace, name, _ := strings.Cut(c.name, "/")
-			source = fmt.Sprintf("%s/terraform-provider-%s", namespace, name)
-		} else {
-			source = fmt.Sprintf("hashicorp/terraform-provider-%s", c.name)
+		// Find the actual source address of the provider from the configuration.
+		// The version doesn't matter here.
+		scanOption, err := tfupdate.NewOption("provider", c.name, v, []string{}, tfregistry.Config{}, tfupdate.UpdatePolicy{NoFormat: c.noFormat}, tfupdate.VersionPolicy{}, tfupdate.LockPolicy{}, tfupdate.WalkPolicy{Recursive: c.recursive, IgnorePaths: c.ignorePaths})
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
 		}
-		r, err := newRelease("github", source)
+
+		address, err := tfupdate.ResolveProviderSource(c.Fs, scanOption, c.path)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+
+		source, err := providerReleaseSource(c.sourceType, address)
 		if err != nil {
 			c.UI.Error(err.Error())
 			return 1
 		}
 
-		v, err = release.Latest(context.Background(), r)
+		r, err := newRelease(c.sourceType, source)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+
+		v, policy, err = latestRelease(context.Background(), r, c.bump)
 		if err != nil {
 			c.UI.Error(err.Error())
 			return 1
 		}
 	}
 
+	policy.PreserveOperators = c.preserveOperators
+	policy.AllowDowngrade = c.allowDowngrade
+
 	log.Printf("[INFO] Update provider %s to %s", c.name, v)
-	option, err := tfupdate.NewOption("provider", c.name, v, []string{}, c.recursive, c.ignorePaths, "", tfregistry.Config{})
+	option, err := tfupdate.NewOption("provider", c.name, v, []string{}, tfregistry.Config{}, tfupdate.UpdatePolicy{NoFormat: c.noFormat}, policy, tfupdate.LockPolicy{}, tfupdate.WalkPolicy{Recursive: c.recursive, IgnorePaths: c.ignorePaths, Jobs: c.jobs, KeepGoing: c.keepGoing})
 	if err != nil {
 		c.UI.Error(err.Error())
 		return 1
 	}
 
-	gc, err := tfupdate.NewGlobalContext(c.Fs, option)
+	gc, err := tfupdate.NewGlobalContext(c.updateFs(c.check), option)
 	if err != nil {
 		c.UI.Error(err.Error())
 		return 1
@@ -84,7 +135,26 @@ func (c *ProviderCommand) Run(args []string) int {
 		return 1
 	}
 
-	return 0
+	return c.outputResults(gc, c.check, c.diff, c.format)
+}
+
+// providerReleaseSource returns a path of release data source for a given
+// provider source address. The format depends on the sourceType.
+func providerReleaseSource(sourceType string, address string) (string, error) {
+	switch sourceType {
+	case "tfregistryProvider":
+		// [HOSTNAME/]NAMESPACE/TYPE
+		return address, nil
+	case "github", "gitlab":
+		// Guess a repository name from the provider address.
+		// Note that this doesn't work if the repository name doesn't follow the
+		// naming convention.
+		s := strings.Split(address, "/")
+		namespace, providerType := s[len(s)-2], s[len(s)-1]
+		return fmt.Sprintf("%s/terraform-provider-%s", namespace, providerType), nil
+	default:
+		return "", fmt.Errorf("unknown source type for provider: %s. Valid values are \"tfregistryProvider\", \"github\" or \"gitlab\"", sourceType)
+	}
 }
 
 // Help returns long-form help text.
@@ -99,9 +169,40 @@ Arguments
 Options:
   -v  --version      A new version constraint (default: latest)
                      If the version is omitted, the latest version is automatically checked and set.
+                     For a short name, the source address is resolved from required_providers in the PATH.
+  -s  --source-type  A type of release data source for resolving the latest version.
+                     Valid values are
+                       - tfregistryProvider (default)
+                       - github
+                       - gitlab
+                     The tfregistryProvider fetches versions from the registry with the provider address.
+                     The github and gitlab guess a repository name as <NAMESPACE>/terraform-provider-<TYPE>.
   -r  --recursive    Check a directory recursively (default: false)
   -i  --ignore-path  A regular expression for path to ignore
                      If you want to ignore multiple directories, set the flag multiple times.
+  -j  --jobs         A number of modules processed concurrently in a recursive mode (default: 1)
+                     If greater than 1, an error in a module doesn't stop processing the others,
+                     and all errors are reported at the end.
+      --keep-going   Continue updating other files when a file fails to update (default: false)
+                     Failed files are reported at the end, and the command exits with status 1 if any.
+      --bump         A level of version bump. Valid values are patch, minor or major
+                     The newest release within the current minor version (patch), major version (minor)
+                     or any version (major) is selected for each file. It never downgrades.
+                     It cannot be used with --version.
+      --preserve-operators
+                     Rewrite only version operands and keep operators in the current constraint (default: false)
+                     For example, "~> 4.0" becomes "~> 5.1" and ">= 1.5, < 2.0" becomes ">= 2.3, < 3.0".
+      --allow-downgrade
+                     Allow updating to a lower version than the current one (default: false)
+                     By default, such updates are skipped with a warning.
+      --check        Check whether updates are pending without writing files (default: false)
+                     List files to be updated and exit with status 2 if any.
+      --diff         Show a unified diff of updated files (default: false)
+                     Use with --check to preview changes without writing files.
+      --format       An output format. Valid values are "text" or "json" (default: text)
+                     The json format outputs a list of changed attributes in all files.
+      --no-format    Rewrite only changed values without formatting files (default: false)
+                     By default, updated files are formatted as terraform fmt does.
 `
 	return strings.TrimSpace(helpText)
 }
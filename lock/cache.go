@@ -0,0 +1,135 @@
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Cache is a persistent data store for caching hash values of provider
+// packages across processes. Unlike the Index, which only lives in memory, it
+// allows us to skip downloading provider packages already seen.
+type Cache interface {
+	// Get returns a cached entry for a given provider package.
+	// If not found, it returns false.
+	// address is a provider address such as hashicorp/null.
+	// version is a version number such as 3.2.1.
+	// platform is a target platform name such as darwin_arm64.
+	Get(address string, version string, platform string) (*CacheEntry, bool)
+
+	// Put stores an entry for a given provider package.
+	Put(address string, version string, platform string, entry *CacheEntry) error
+}
+
+// CacheEntry is a set of data for calculating hash values of a provider
+// package for a specific platform.
+type CacheEntry struct {
+	// Filename is a filename of the provider package such as
+	// terraform-provider-null_3.2.1_darwin_arm64.zip.
+	Filename string `json:"filename"`
+
+	// H1Hash is a h1 hash value calculated from the provider package.
+	H1Hash string `json:"h1"`
+
+	// ShaSumsData is the contents of the SHA256SUMS document, which is used for
+	// calculating zh hash values for all platforms.
+	ShaSumsData string `json:"shasums"`
+}
+
+// diskCache is an implementation for Cache interface which stores entries as
+// JSON files in a local directory.
+// The path of each entry is derived from the key as follows:
+// <dir>/<namespace>/<type>/<version>/<platform>.json
+type diskCache struct {
+	// dir is a root directory of the cache.
+	dir string
+}
+
+var _ Cache = (*diskCache)(nil)
+
+// NewDiskCache returns a new instance of Cache which stores entries in a
+// given directory. The directory is created on demand.
+func NewDiskCache(dir string) Cache {
+	return &diskCache{
+		dir: dir,
+	}
+}
+
+// Get returns a cached entry for a given provider package.
+func (c *diskCache) Get(address string, version string, platform string) (*CacheEntry, bool) {
+	path, err := c.path(address, version, platform)
+	if err != nil {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// A broken entry is treated as a cache miss and overwritten later.
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+// Put stores an entry for a given provider package.
+// To avoid reading a partially written entry, it writes to a temporary file
+// first and then renames it.
+func (c *diskCache) Put(address string, version string, platform string, entry *CacheEntry) error {
+	path, err := c.path(address, version, platform)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %s", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %s", err)
+	}
+
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %s", err)
+	}
+	tmp := f.Name()
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write cache file: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write cache file: %s", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write cache file: %s", err)
+	}
+
+	return nil
+}
+
+// path returns a path of the cache entry for a given key.
+func (c *diskCache) path(address string, version string, platform string) (string, error) {
+	pAddr, err := parseProviderAddress(address)
+	if err != nil {
+		return "", err
+	}
+
+	if _, _, err := parseProviderPlatform(platform); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(c.dir, pAddr.Namespace, pAddr.Type, version, platform+".json"), nil
+}
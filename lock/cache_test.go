@@ -0,0 +1,103 @@
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiskCache(t *testing.T) {
+	cases := []struct {
+		desc     string
+		address  string
+		version  string
+		platform string
+		entry    *CacheEntry
+		path     string
+		ok       bool
+	}{
+		{
+			desc:     "simple",
+			address:  "hashicorp/null",
+			version:  "3.2.1",
+			platform: "darwin_arm64",
+			entry: &CacheEntry{
+				Filename:    "terraform-provider-null_3.2.1_darwin_arm64.zip",
+				H1Hash:      "h1:3323G20HW9PA9ONrL6CdQCdCFe6y94kXeOTprq+Zu+w=",
+				ShaSumsData: "5622a0fd03420ed1fa83a1a6e90b65fbe34bc74c251b3b47048f14217e93b086  terraform-provider-null_3.2.1_darwin_arm64.zip",
+			},
+			path: "hashicorp/null/3.2.1/darwin_arm64.json",
+			ok:   true,
+		},
+		{
+			desc:     "invalid address",
+			address:  "null",
+			version:  "3.2.1",
+			platform: "darwin_arm64",
+			entry:    &CacheEntry{},
+			ok:       false,
+		},
+		{
+			desc:     "invalid platform",
+			address:  "hashicorp/null",
+			version:  "3.2.1",
+			platform: "darwin",
+			entry:    &CacheEntry{},
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			c := NewDiskCache(dir)
+
+			if _, ok := c.Get(tc.address, tc.version, tc.platform); ok {
+				t.Fatalf("expected cache miss before Put, but hit")
+			}
+
+			err := c.Put(tc.address, tc.version, tc.platform, tc.entry)
+			if tc.ok && err != nil {
+				t.Fatalf("failed to call Put: err = %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("expected to fail, but success")
+				}
+				return
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, tc.path)); err != nil {
+				t.Errorf("expected a cache file at %s, but got err = %s", tc.path, err)
+			}
+
+			got, ok := c.Get(tc.address, tc.version, tc.platform)
+			if !ok {
+				t.Fatalf("expected cache hit after Put, but miss")
+			}
+
+			if diff := cmp.Diff(got, tc.entry); diff != "" {
+				t.Errorf("got: %#v, want = %#v, diff = %s", got, tc.entry, diff)
+			}
+		})
+	}
+}
+
+func TestDiskCacheBrokenEntry(t *testing.T) {
+	dir := t.TempDir()
+	c := NewDiskCache(dir)
+
+	path := filepath.Join(dir, "hashicorp/null/3.2.1/darwin_arm64.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create dir: %s", err)
+	}
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+
+	if got, ok := c.Get("hashicorp/null", "3.2.1", "darwin_arm64"); ok {
+		t.Errorf("expected a broken entry to be a cache miss, but got: %#v", got)
+	}
+}
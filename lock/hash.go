@@ -3,6 +3,7 @@ package lock
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"golang.org/x/mod/sumdb/dirhash"
@@ -26,6 +27,25 @@ func zipDataToH1Hash(zipData []byte) (string, error) {
 	return hash, nil
 }
 
+// dirToH1Hash is a helper function that calculates the h1 hash value from an
+// unpacked provider package directory, such as one in TF_PLUGIN_CACHE_DIR.
+func dirToH1Hash(dir string) (string, error) {
+	// The directory in .terraform/providers may be a symbolic link to the
+	// plugin cache directory.
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory: %s", err)
+	}
+
+	// The h1 hash value of the directory is the same as the zip archive.
+	hash, err := dirhash.HashDir(resolved, "", dirhash.Hash1)
+	if err != nil {
+		return "", fmt.Errorf("failed to calculate h1 hash: %s", err)
+	}
+
+	return hash, nil
+}
+
 // writeTempFile writes content to a temporary file and return its file.
 func writeTempFile(content []byte) (*os.File, error) {
 	tmpfile, err := os.CreateTemp("", "tmp")
@@ -1,6 +1,8 @@
 package lock
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
@@ -87,6 +89,59 @@ func TestZipDataToH1Hash(t *testing.T) {
 	}
 }
 
+func TestDirToH1Hash(t *testing.T) {
+	cases := []struct {
+		desc     string
+		makeDir  bool
+		contents string
+		want     string
+		ok       bool
+	}{
+		{
+			desc:     "darwin_arm64",
+			makeDir:  true,
+			contents: "dummy_3.2.1_darwin_arm64",
+			// The same as the h1 hash value calculated from the zip archive.
+			want: "h1:3323G20HW9PA9ONrL6CdQCdCFe6y94kXeOTprq+Zu+w=",
+			ok:   true,
+		},
+		{
+			desc:    "not found",
+			makeDir: false,
+			want:    "",
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "darwin_arm64")
+			if tc.makeDir {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "terraform-provider-dummy_v3.2.1_x5"), []byte(tc.contents), 0755); err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			got, err := dirToH1Hash(dir)
+
+			if tc.ok && err != nil {
+				t.Fatalf("failed to call dirToH1Hash: err = %s", err)
+			}
+
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to fail, but success: got = %s", got)
+			}
+
+			if got != tc.want {
+				t.Errorf("got=%s, but want=%s", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestShaSumsDataToZhHash(t *testing.T) {
 	// create a valid dummy shaSumsData.
 	platforms := []string{"darwin_arm64", "darwin_amd64", "linux_amd64", "windows_amd64"}
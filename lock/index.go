@@ -5,15 +5,23 @@ import (
 	"fmt"
 	"log"
 	"maps"
+	"net/url"
+	"path/filepath"
 	"runtime"
 	"slices"
 	"strings"
+	"sync"
 
 	tfaddr "github.com/hashicorp/terraform-registry-address"
 	"github.com/minamijoyo/tfupdate/tfregistry"
 )
 
+// defaultParallelism is the default number of provider packages downloaded
+// concurrently.
+const defaultParallelism = 4
+
 // Index is an in-memory data store for caching provider hash values.
+// It is safe for concurrent use by multiple goroutines.
 type Index interface {
 	// GetOrCreateProviderVersion returns a cached provider version if available,
 	// otherwise creates it.
@@ -21,51 +29,113 @@ type Index interface {
 	// version is a version number such as 3.2.1.
 	// platforms is a list of target platforms to generate hash values.
 	// Target platform names consist of an operating system and a CPU architecture such as darwin_arm64.
-	GetOrCreateProviderVersion(ctx context.Context, address string, version string, platforms []string) (*ProviderVersion, error)
+	// pluginDirs is a list of additional local directories to look up unpacked
+	// provider packages, such as .terraform/providers in the module. It may be nil.
+	GetOrCreateProviderVersion(ctx context.Context, address string, version string, platforms []string, pluginDirs []string) (*ProviderVersion, error)
 }
 
 // index is an implementation for Index interface.
 type index struct {
+	// mu protects providers.
+	mu sync.Mutex
+
 	// providers is a dictionary of providerIndex.
 	// The key is a provider address such as hashicorp/null.
 	providers map[string]*providerIndex
 
 	// papi is a ProviderLockAPI interface implementation used for locking provider.
 	papi ProviderLockAPI
+
+	// cache is a persistent cache for hash values of provider packages.
+	// If nil, the cache is disabled.
+	cache Cache
+
+	// sem is a semaphore shared by all providers to limit the number of
+	// provider packages downloaded concurrently.
+	sem chan struct{}
 }
 
 // NewIndexFromConfig returns a new instance of Index with the given registry config.
-func NewIndexFromConfig(config tfregistry.Config) (Index, error) {
-	client, err := NewProviderLockClient(config)
+// If cacheDir is not empty, hash values of downloaded provider packages are
+// persisted in the directory and reused across processes.
+// The pluginDirs is a list of local directories to look up unpacked provider
+// packages before downloading them.
+// The parallelism is the maximum number of provider packages downloaded
+// concurrently. If less than 1, the default value is used.
+func NewIndexFromConfig(config tfregistry.Config, cacheDir string, pluginDirs []string, parallelism int) (Index, error) {
+	client, err := NewProviderLockClient(config, pluginDirs)
 	if err != nil {
 		return nil, err
 	}
 
-	index := NewIndex(client)
+	var cache Cache
+	if cacheDir != "" {
+		// Hash values may differ between registries, so separate the cache
+		// directory by the hostname of the registry.
+		hostname, err := registryHostname(config)
+		if err != nil {
+			return nil, err
+		}
+		cache = NewDiskCache(filepath.Join(cacheDir, hostname))
+	}
+
+	index := NewIndexWithCache(client, cache, parallelism)
 
 	return index, nil
 }
 
 // NewIndex returns a new instance of Index with the given ProviderLockAPI.
 func NewIndex(papi ProviderLockAPI) Index {
+	return NewIndexWithCache(papi, nil, defaultParallelism)
+}
+
+// NewIndexWithCache returns a new instance of Index with the given
+// ProviderLockAPI and Cache. If the cache is nil, it is disabled.
+// The parallelism is the maximum number of provider packages downloaded
+// concurrently. If less than 1, the default value is used.
+func NewIndexWithCache(papi ProviderLockAPI, cache Cache, parallelism int) Index {
+	if parallelism < 1 {
+		parallelism = defaultParallelism
+	}
+
 	providers := make(map[string]*providerIndex)
 	return &index{
 		providers: providers,
 		papi:      papi,
+		cache:     cache,
+		sem:       make(chan struct{}, parallelism),
 	}
 }
 
+// registryHostname returns the hostname of the registry in the given config.
+func registryHostname(config tfregistry.Config) (string, error) {
+	if config.BaseURL == "" {
+		// the public Terraform Registry
+		return "registry.terraform.io", nil
+	}
+
+	u, err := url.Parse(config.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse base url: %s", err)
+	}
+
+	return u.Hostname(), nil
+}
+
 // GetOrCreateProviderVersion returns a cached provider version if available,
 // otherwise creates it.
-func (i *index) GetOrCreateProviderVersion(ctx context.Context, address string, version string, platforms []string) (*ProviderVersion, error) {
+func (i *index) GetOrCreateProviderVersion(ctx context.Context, address string, version string, platforms []string, pluginDirs []string) (*ProviderVersion, error) {
+	i.mu.Lock()
 	pi, ok := i.providers[address]
 	if !ok {
 		// cache miss
-		pi = newProviderIndex(address, i.papi)
+		pi = newProviderIndex(address, i.papi, i.cache, i.sem)
 		i.providers[address] = pi
 	}
+	i.mu.Unlock()
+
 	// Delegate to ProviderIndex.
-	return pi.getOrCreateProviderVersion(ctx, version, platforms)
+	return pi.getOrCreateProviderVersion(ctx, version, platforms, pluginDirs)
 }
 
 // The providerIndex holds multiple version data for a specific provider.
@@ -73,43 +143,106 @@ type providerIndex struct {
 	// address is a provider address such as hashicorp/null.
 	address string
 
+	// mu protects versions and calls.
+	mu sync.Mutex
+
 	// versions is a dictionary of ProviderVersion.
 	// The key is a version number such as 3.2.1.
 	versions map[string]*ProviderVersion
 
+	// calls is a dictionary of in-flight calls for creating ProviderVersion.
+	// The key is a version number such as 3.2.1.
+	calls map[string]*providerVersionCall
+
 	// papi is a ProviderLockAPI interface implementation used for locking provider.
 	papi ProviderLockAPI
+
+	// cache is a persistent cache for hash values of provider packages.
+	// If nil, the cache is disabled.
+	cache Cache
+
+	// sem is a semaphore to limit the number of provider packages downloaded
+	// concurrently.
+	sem chan struct{}
+}
+
+// providerVersionCall is an in-flight call for creating ProviderVersion.
+// It is used to deduplicate concurrent calls for the same version.
+type providerVersionCall struct {
+	// done is closed when the call is completed.
+	done chan struct{}
+
+	// pv is a result of the call.
+	pv *ProviderVersion
+
+	// err is an error of the call.
+	err error
 }
 
 // newProviderIndex returns a new instance of providerIndex.
-func newProviderIndex(address string, papi ProviderLockAPI) *providerIndex {
+// If the sem is nil, provider packages are downloaded one by one.
+func newProviderIndex(address string, papi ProviderLockAPI, cache Cache, sem chan struct{}) *providerIndex {
+	if sem == nil {
+		sem = make(chan struct{}, 1)
+	}
+
 	versions := make(map[string]*ProviderVersion)
+	calls := make(map[string]*providerVersionCall)
 	return &providerIndex{
 		address:  address,
 		versions: versions,
+		calls:    calls,
 		papi:     papi,
+		cache:    cache,
+		sem:      sem,
 	}
 }
 
 // getOrCreateProviderVersion returns a cached provider version if available,
-// otherwise creates it.
-func (pi *providerIndex) getOrCreateProviderVersion(ctx context.Context, version string, platforms []string) (*ProviderVersion, error) {
-	pv, ok := pi.versions[version]
-	if !ok {
-		// cache miss
-		var err error
-		pv, err = pi.createProviderVersion(ctx, version, platforms)
-		if err != nil {
-			return nil, err
+// otherwise creates it. If another goroutine is already creating the same
+// version, it waits for the result instead of downloading it again.
+func (pi *providerIndex) getOrCreateProviderVersion(ctx context.Context, version string, platforms []string, pluginDirs []string) (*ProviderVersion, error) {
+	pi.mu.Lock()
+	if pv, ok := pi.versions[version]; ok {
+		pi.mu.Unlock()
+		return pv, nil
+	}
+
+	if c, ok := pi.calls[version]; ok {
+		pi.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.pv, c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
-		pi.versions[version] = pv
 	}
-	return pv, nil
+
+	// cache miss
+	c := &providerVersionCall{done: make(chan struct{})}
+	pi.calls[version] = c
+	pi.mu.Unlock()
+
+	c.pv, c.err = pi.createProviderVersion(ctx, version, platforms, pluginDirs)
+
+	pi.mu.Lock()
+	if c.err == nil {
+		pi.versions[version] = c.pv
+	}
+	// Errors are not cached so that the next call can retry.
+	delete(pi.calls, version)
+	pi.mu.Unlock()
+	close(c.done)
+
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.pv, nil
 }
 
 // createProviderVersion downloads the specified provider, calculates the hash
 // value and returns an instance of the ProviderVersion.
-func (pi *providerIndex) createProviderVersion(ctx context.Context, version string, platforms []string) (*ProviderVersion, error) {
+func (pi *providerIndex) createProviderVersion(ctx context.Context, version string, platforms []string, pluginDirs []string) (*ProviderVersion, error) {
 	// Starting with OpenTofu v1.12, the OpenTofu Registry now returns both the
 	// zh hash and the precomputed h1 hash, so fetching only the metadata allows
 	// us to skip downloading the provider’s binary.
@@ -131,35 +264,92 @@ func (pi *providerIndex) createProviderVersion(ctx context.Context, version stri
 		return pv, nil
 	}
 
+	// Currently the Terraform Registry returns the zh hash for all platforms,
+	// but not the h1 hash, so the h1 hash has to be calculated separately.
+	// We need to calculate the values for each platform and merge the results.
+	// Since downloading is slow, platforms are downloaded in parallel.
+	pvs := make([]*ProviderVersion, len(platforms))
+	errs := make([]error, len(platforms))
+	var wg sync.WaitGroup
+	for i, platform := range platforms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case pi.sem <- struct{}{}:
+				defer func() { <-pi.sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			pvs[i], errs[i] = pi.downloadProviderVersion(ctx, version, platform, pluginDirs)
+		}()
+	}
+	wg.Wait()
+
+	// Merge the results in the order of platforms to make the result deterministic.
 	ret := newEmptyProviderVersion(pi.address, version)
-	for _, platform := range platforms {
-		req, err := newProviderDownloadRequest(pi.address, version, platform)
-		if err != nil {
-			return nil, err
+	for i := range platforms {
+		if errs[i] != nil {
+			return nil, errs[i]
 		}
 
-		// Download a given provider from registry.
-		log.Printf("[DEBUG] providerIndex.createProviderVersion: %s, %s, %s", pi.address, version, platform)
-		res, err := pi.papi.ProviderDownload(ctx, req)
+		err := ret.Merge(pvs[i])
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		// Currently the Terraform Registry returns the zh hash for all platforms,
-		// but not the h1 hash, so the h1 hash has to be calculated separately.
-		// We need to calculate the values for each platform and merge the results.
-		pv, err := buildProviderVersionFromDownload(pi.address, version, platform, res)
-		if err != nil {
-			return nil, err
+	return ret, nil
+}
+
+// downloadProviderVersion downloads the specified provider for a given
+// platform, calculates the hash value and returns an instance of the
+// ProviderVersion. If the persistent cache is enabled and has an entry for the
+// provider package, it skips downloading.
+// The pluginDirs is a list of additional local directories to look up an
+// unpacked provider package.
+func (pi *providerIndex) downloadProviderVersion(ctx context.Context, version string, platform string, pluginDirs []string) (*ProviderVersion, error) {
+	if pi.cache != nil {
+		if entry, ok := pi.cache.Get(pi.address, version, platform); ok {
+			log.Printf("[DEBUG] providerIndex.downloadProviderVersion: cache hit: %s, %s, %s", pi.address, version, platform)
+			return buildProviderVersionFromHashes(pi.address, version, platform, entry.Filename, entry.H1Hash, []byte(entry.ShaSumsData))
 		}
+	}
 
-		err = ret.Merge(pv)
-		if err != nil {
-			return nil, err
+	req, err := newProviderDownloadRequest(pi.address, version, platform)
+	if err != nil {
+		return nil, err
+	}
+	req.PluginDirs = pluginDirs
+
+	// Download a given provider from registry.
+	log.Printf("[DEBUG] providerIndex.downloadProviderVersion: %s, %s, %s", pi.address, version, platform)
+	res, err := pi.papi.ProviderDownload(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	pv, err := buildProviderVersionFromDownload(pi.address, version, platform, res)
+	if err != nil {
+		return nil, err
+	}
+
+	// A hash value calculated from a local provider package is not verified
+	// against the registry, so don't persist it for other processes.
+	if pi.cache != nil && res.h1Hash == "" {
+		entry := &CacheEntry{
+			Filename:    res.filename,
+			H1Hash:      pv.h1Hashes[res.filename],
+			ShaSumsData: string(res.shaSumsData),
+		}
+		// The cache is just an optimization, so ignore errors.
+		if err := pi.cache.Put(pi.address, version, platform, entry); err != nil {
+			log.Printf("[WARN] failed to write cache: %s, %s, %s: %s", pi.address, version, platform, err)
 		}
 	}
 
-	return ret, nil
+	return pv, nil
 }
 
 // fetchProviderPackageMetadata fetches the provider package metadata from the registry and returns an instance of the ProviderVersion.
@@ -318,15 +508,25 @@ func newProviderDownloadRequest(address string, version string, platform string)
 // buildProviderVersionFromDownload calculates hash values from the ProviderDownloadResponse
 // and returns an instance of the ProviderVersion.
 func buildProviderVersionFromDownload(address string, version string, platform string, res *ProviderDownloadResponse) (*ProviderVersion, error) {
-	h1Hashes := make(map[string]string)
-
-	h1, err := zipDataToH1Hash(res.zipData)
-	if err != nil {
-		return nil, err
+	h1 := res.h1Hash
+	if h1 == "" {
+		var err error
+		h1, err = zipDataToH1Hash(res.zipData)
+		if err != nil {
+			return nil, err
+		}
 	}
-	h1Hashes[res.filename] = h1
 
-	zhHashes, err := shaSumsDataToZhHash(res.shaSumsData)
+	return buildProviderVersionFromHashes(address, version, platform, res.filename, h1, res.shaSumsData)
+}
+
+// buildProviderVersionFromHashes returns an instance of the ProviderVersion
+// from the h1 hash of the provider package and the SHA256SUMS document.
+func buildProviderVersionFromHashes(address string, version string, platform string, filename string, h1 string, shaSumsData []byte) (*ProviderVersion, error) {
+	h1Hashes := make(map[string]string)
+	h1Hashes[filename] = h1
+
+	zhHashes, err := shaSumsDataToZhHash(shaSumsData)
 	if err != nil {
 		return nil, err
 	}
@@ -2,7 +2,10 @@ package lock
 
 import (
 	"context"
+	"fmt"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
@@ -10,7 +13,10 @@ import (
 )
 
 // mockProviderLockClient is a mock ProviderLockAPI implementation.
+// Since platforms are downloaded concurrently, it returns the first response
+// for the requested platform regardless of the order of calls.
 type mockProviderLockClient struct {
+	mu          sync.Mutex
 	called      int
 	metadataRes *ProviderPackageMetadataResponse
 	responses   []*ProviderDownloadResponse
@@ -23,11 +29,18 @@ func (c *mockProviderLockClient) ProviderPackageMetadata(_ context.Context, _ *P
 	return c.metadataRes, nil
 }
 
-func (c *mockProviderLockClient) ProviderDownload(_ context.Context, _ *ProviderDownloadRequest) (*ProviderDownloadResponse, error) {
-	res := c.responses[c.called]
-	err := c.errs[c.called]
+func (c *mockProviderLockClient) ProviderDownload(_ context.Context, req *ProviderDownloadRequest) (*ProviderDownloadResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.called++
-	return res, err
+	suffix := fmt.Sprintf("_%s_%s.zip", req.OS, req.Arch)
+	for i, res := range c.responses {
+		if strings.HasSuffix(res.filename, suffix) {
+			return res, c.errs[i]
+		}
+	}
+	return nil, fmt.Errorf("unexpected request: %#v", req)
 }
 
 func TestIndexGetOrCreateProviderVersion(t *testing.T) {
@@ -53,7 +66,7 @@ func TestIndexGetOrCreateProviderVersion(t *testing.T) {
 			client.called = 0
 
 			// 1st call
-			_, err = index.GetOrCreateProviderVersion(context.Background(), address, version, targetPlatforms)
+			_, err = index.GetOrCreateProviderVersion(context.Background(), address, version, targetPlatforms, nil)
 			if err != nil {
 				t.Fatalf("%s@%s: failed to call GetOrCreateProviderVersion: err = %s", address, version, err)
 			}
@@ -63,7 +76,7 @@ func TestIndexGetOrCreateProviderVersion(t *testing.T) {
 			}
 
 			// 2nd call
-			_, err = index.GetOrCreateProviderVersion(context.Background(), address, version, targetPlatforms)
+			_, err = index.GetOrCreateProviderVersion(context.Background(), address, version, targetPlatforms, nil)
 			if err != nil {
 				t.Fatalf("%s@%s: failed to call GetOrCreateProviderVersion: err = %s", address, version, err)
 			}
@@ -126,10 +139,10 @@ func TestProviderIndexGetOrCreateProviderVersion(t *testing.T) {
 				responses: mockResponses,
 				errs:      mockNoErrors,
 			}
-			pi := newProviderIndex(tc.address, client)
+			pi := newProviderIndex(tc.address, client, nil, nil)
 
 			// 1st call
-			got, err := pi.getOrCreateProviderVersion(context.Background(), tc.version, tc.platforms)
+			got, err := pi.getOrCreateProviderVersion(context.Background(), tc.version, tc.platforms, nil)
 
 			if tc.ok && err != nil {
 				t.Fatalf("failed to call getOrCreateProviderVersion: err = %s", err)
@@ -149,7 +162,7 @@ func TestProviderIndexGetOrCreateProviderVersion(t *testing.T) {
 			}
 
 			// 2nd call
-			cached, err := pi.getOrCreateProviderVersion(context.Background(), tc.version, tc.platforms)
+			cached, err := pi.getOrCreateProviderVersion(context.Background(), tc.version, tc.platforms, nil)
 
 			if tc.ok && err != nil {
 				t.Fatalf("failed to call getOrCreateProviderVersion: err = %s", err)
@@ -171,6 +184,126 @@ func TestProviderIndexGetOrCreateProviderVersion(t *testing.T) {
 	}
 }
 
+func TestIndexGetOrCreateProviderVersionConcurrent(t *testing.T) {
+	address := "minamijoyo/dummy"
+	version := "3.2.1"
+	platforms := []string{"darwin_arm64", "darwin_amd64", "linux_amd64", "windows_amd64"}
+
+	res, err := newMockProviderDownloadResponses(address, version, platforms, platforms)
+	if err != nil {
+		t.Fatalf("failed to create mockResponses: err = %s", err)
+	}
+	client := &mockProviderLockClient{
+		responses: res,
+		errs:      make([]error, len(platforms)),
+	}
+	index := NewIndexWithCache(client, nil, 2)
+
+	// Concurrent calls for the same version should be deduplicated.
+	n := 10
+	got := make([]*ProviderVersion, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[i], errs[i] = index.GetOrCreateProviderVersion(context.Background(), address, version, platforms, nil)
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatalf("failed to call GetOrCreateProviderVersion: err = %s", errs[i])
+		}
+		if diff := cmp.Diff(got[i], got[0], cmp.AllowUnexported(ProviderVersion{})); diff != "" {
+			t.Errorf("got: %s, want = %s, diff = %s", spew.Sdump(got[i]), spew.Sdump(got[0]), diff)
+		}
+	}
+
+	// The result should be merged in the order of platforms.
+	if diff := cmp.Diff(got[0].platforms, platforms); diff != "" {
+		t.Errorf("got platforms: %#v, want = %#v, diff = %s", got[0].platforms, platforms, diff)
+	}
+
+	if client.called != len(platforms) {
+		t.Fatalf("api was called %d times, but expected to be called %d times", client.called, len(platforms))
+	}
+}
+
+func TestProviderIndexGetOrCreateProviderVersionWithCache(t *testing.T) {
+	address := "minamijoyo/dummy"
+	version := "3.2.1"
+	platforms := []string{"darwin_arm64", "linux_amd64"}
+	allPlatforms := []string{"darwin_arm64", "darwin_amd64", "linux_amd64", "windows_amd64"}
+
+	res, err := newMockProviderDownloadResponses(address, version, platforms, allPlatforms)
+	if err != nil {
+		t.Fatalf("failed to create mockResponses: err = %s", err)
+	}
+	client := &mockProviderLockClient{
+		responses: res,
+		errs:      make([]error, len(platforms)),
+	}
+	cache := NewDiskCache(t.TempDir())
+
+	// 1st process downloads provider packages and writes the cache.
+	pi := newProviderIndex(address, client, cache, nil)
+	want, err := pi.getOrCreateProviderVersion(context.Background(), version, platforms, nil)
+	if err != nil {
+		t.Fatalf("failed to call getOrCreateProviderVersion: err = %s", err)
+	}
+	if client.called != len(platforms) {
+		t.Fatalf("api was called %d times, but expected to be called %d times", client.called, len(platforms))
+	}
+
+	// 2nd process has an empty in-memory index, but reads the cache.
+	pi = newProviderIndex(address, client, cache, nil)
+	got, err := pi.getOrCreateProviderVersion(context.Background(), version, platforms, nil)
+	if err != nil {
+		t.Fatalf("failed to call getOrCreateProviderVersion: err = %s", err)
+	}
+	if client.called != len(platforms) {
+		t.Fatalf("api was called %d times, but expected to be called %d times", client.called, len(platforms))
+	}
+
+	if diff := cmp.Diff(got, want, cmp.AllowUnexported(ProviderVersion{})); diff != "" {
+		t.Errorf("got: %s, want = %s, diff = %s", spew.Sdump(got), spew.Sdump(want), diff)
+	}
+}
+
+func TestProviderIndexGetOrCreateProviderVersionWithCacheLocalHash(t *testing.T) {
+	address := "minamijoyo/dummy"
+	version := "3.2.1"
+	platforms := []string{"darwin_arm64"}
+	allPlatforms := []string{"darwin_arm64", "darwin_amd64", "linux_amd64", "windows_amd64"}
+
+	res, err := newMockProviderDownloadResponses(address, version, platforms, allPlatforms)
+	if err != nil {
+		t.Fatalf("failed to create mockResponses: err = %s", err)
+	}
+	// Simulate a provider package found in a plugin directory.
+	res[0].zipData = nil
+	res[0].h1Hash = "h1:3323G20HW9PA9ONrL6CdQCdCFe6y94kXeOTprq+Zu+w="
+	client := &mockProviderLockClient{
+		responses: res,
+		errs:      make([]error, len(platforms)),
+	}
+	cache := NewDiskCache(t.TempDir())
+
+	// A hash value calculated from a local provider package is not persisted.
+	for range 2 {
+		pi := newProviderIndex(address, client, cache, nil)
+		if _, err := pi.getOrCreateProviderVersion(context.Background(), version, platforms, nil); err != nil {
+			t.Fatalf("failed to call getOrCreateProviderVersion: err = %s", err)
+		}
+	}
+	if client.called != 2 {
+		t.Fatalf("api was called %d times, but expected to be called %d times", client.called, 2)
+	}
+}
+
 func TestNewProviderDownloadRequest(t *testing.T) {
 	cases := []struct {
 		desc     string
@@ -365,7 +498,7 @@ func TestProviderIndexFetchProviderPackageMetadata(t *testing.T) {
 			client := &mockProviderLockClient{
 				metadataRes: res,
 			}
-			pi := newProviderIndex(tc.address, client)
+			pi := newProviderIndex(tc.address, client, nil, nil)
 
 			got, err := pi.fetchProviderPackageMetadata(context.Background(), tc.version, tc.platform)
 
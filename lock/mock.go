@@ -46,7 +46,7 @@ func newMockServer() (*http.ServeMux, *url.URL) {
 // newTestClient returns a new client for testing.
 func newTestClient(mockServerURL *url.URL, config tfregistry.Config) *ProviderLockClient {
 	config.BaseURL = mockServerURL.String()
-	c, _ := NewProviderLockClient(config)
+	c, _ := NewProviderLockClient(config, nil)
 	return c
 }
 
@@ -185,11 +185,12 @@ func NewMockIndex(pvs []*ProviderVersion) Index {
 	i := &index{
 		providers: make(map[string]*providerIndex),
 		papi:      nil,
+		sem:       make(chan struct{}, defaultParallelism),
 	}
 	for _, pv := range pvs {
 		pi, ok := i.providers[pv.address]
 		if !ok {
-			pi = newProviderIndex(pv.address, i.papi)
+			pi = newProviderIndex(pv.address, i.papi, i.cache, i.sem)
 			i.providers[pv.address] = pi
 		}
 		pi.versions[pv.version] = pv
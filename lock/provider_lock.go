@@ -8,6 +8,9 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/minamijoyo/tfupdate/tfregistry"
@@ -34,10 +37,21 @@ type ProviderLockClient struct {
 
 	// httpClient is a http client which communicates with the ProviderLockAPI.
 	httpClient *http.Client
+
+	// pluginDirs is a list of local directories which contain unpacked
+	// provider packages, such as TF_PLUGIN_CACHE_DIR.
+	// They are looked up before downloading provider packages.
+	pluginDirs []string
+
+	// hostname is a hostname of the registry, which is a part of the path in
+	// the plugin directories.
+	hostname string
 }
 
 // NewProviderLockClient is a factory method which returns a ProviderLockClient instance.
-func NewProviderLockClient(config tfregistry.Config) (*ProviderLockClient, error) {
+// The pluginDirs is a list of local directories to look up unpacked provider
+// packages before downloading them.
+func NewProviderLockClient(config tfregistry.Config, pluginDirs []string) (*ProviderLockClient, error) {
 	api, err := tfregistry.NewClient(config)
 	if err != nil {
 		return nil, err
@@ -48,9 +62,16 @@ func NewProviderLockClient(config tfregistry.Config) (*ProviderLockClient, error
 		httpClient = &http.Client{}
 	}
 
+	hostname, err := registryHostname(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &ProviderLockClient{
 		api:        api,
 		httpClient: httpClient,
+		pluginDirs: pluginDirs,
+		hostname:   hostname,
 	}, nil
 }
 
@@ -80,6 +101,8 @@ type ProviderDownloadRequest struct {
 	OS string `json:"os"`
 	// (required): a keyword identifying the CPU architecture that the returned package should be compatible with, like "amd64" or "arm".
 	Arch string `json:"arch"`
+	// (optional): a list of local directories to look up an unpacked provider package in addition to the plugin directories of the client.
+	PluginDirs []string `json:"-"`
 }
 
 // ProviderDownloadResponse is a response type for ProviderDownload.
@@ -88,13 +111,22 @@ type ProviderDownloadResponse struct {
 	filename string
 
 	// zipData is the raw byte sequence of the provider package.
+	// It is empty if the provider package is found in the plugin directories.
 	zipData []byte
 
+	// h1Hash is the h1 hash value calculated from the unpacked provider package
+	// found in the plugin directories. It is empty if downloaded.
+	h1Hash string
+
 	// shaSumsData is the raw byte sequence of the provider shasum file.
 	shaSumsData []byte
 }
 
 // ProviderDownload downloads a provider package.
+// If an unpacked provider package is found in the plugin directories, it
+// skips downloading the provider package and calculates the h1 hash value from
+// the directory. Note that the SHA256SUMS document is always downloaded to
+// calculate the zh hash values for all platforms.
 func (c *ProviderLockClient) ProviderDownload(ctx context.Context, req *ProviderDownloadRequest) (*ProviderDownloadResponse, error) {
 	metadataReq := &tfregistry.ProviderPackageMetadataRequest{
 		Namespace: req.Namespace,
@@ -109,15 +141,20 @@ func (c *ProviderLockClient) ProviderDownload(ctx context.Context, req *Provider
 		return nil, err
 	}
 
-	downloadURL := metadataRes.DownloadURL
-	zipData, err := c.download(ctx, downloadURL)
-	if err != nil {
-		return nil, err
-	}
+	h1Hash, found := c.findLocalProviderHash(req)
 
-	err = validateSHA256Sum(zipData, metadataRes.SHASum)
-	if err != nil {
-		return nil, err
+	var zipData []byte
+	if !found {
+		downloadURL := metadataRes.DownloadURL
+		zipData, err = c.download(ctx, downloadURL)
+		if err != nil {
+			return nil, err
+		}
+
+		err = validateSHA256Sum(zipData, metadataRes.SHASum)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	shaSumsURL := metadataRes.SHASumsURL
@@ -134,12 +171,40 @@ func (c *ProviderLockClient) ProviderDownload(ctx context.Context, req *Provider
 	ret := &ProviderDownloadResponse{
 		filename:    metadataRes.Filename,
 		zipData:     zipData,
+		h1Hash:      h1Hash,
 		shaSumsData: shaSumsData,
 	}
 
 	return ret, nil
 }
 
+// findLocalProviderHash looks up an unpacked provider package in the plugin
+// directories of the client and the request, and returns its h1 hash value.
+// If not found, it returns false.
+// The plugin directories have the same layout as TF_PLUGIN_CACHE_DIR:
+// <dir>/<hostname>/<namespace>/<type>/<version>/<os>_<arch>
+func (c *ProviderLockClient) findLocalProviderHash(req *ProviderDownloadRequest) (string, bool) {
+	for _, pluginDir := range slices.Concat(c.pluginDirs, req.PluginDirs) {
+		dir := filepath.Join(pluginDir, c.hostname, req.Namespace, req.Type, req.Version, req.OS+"_"+req.Arch)
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+
+		h1Hash, err := dirToH1Hash(dir)
+		if err != nil {
+			// Fall back to downloading.
+			log.Printf("[WARN] failed to calculate h1 hash from a local provider package: %s", err)
+			continue
+		}
+
+		log.Printf("[DEBUG] ProviderLockClient.findLocalProviderHash: found a local provider package: %s", dir)
+		return h1Hash, true
+	}
+
+	return "", false
+}
+
 // download is a helper function that downloads contents from a given URL.
 func (c *ProviderLockClient) download(ctx context.Context, url string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -118,6 +120,113 @@ fc5bbdd0a1bd6715b9afddf3aba6acc494425d77015c19579b9a9fa950e532b2  terraform-prov
 	}
 }
 
+func TestProviderLockClientProviderDownloadFromPluginDir(t *testing.T) {
+	// The provider package is not served, so it fails if downloaded.
+	downloadPath := "/terraform-provider-dummy/3.2.1/terraform-provider-dummy_3.2.1_darwin_arm64.zip"
+	shaSumsPath := "/terraform-provider-dummy/3.2.1/terraform-provider-dummy_3.2.1_SHA256SUMS"
+
+	shaSumsData := []byte(`
+5622a0fd03420ed1fa83a1a6e90b65fbe34bc74c251b3b47048f14217e93b086  terraform-provider-dummy_3.2.1_darwin_arm64.zip
+fc5bbdd0a1bd6715b9afddf3aba6acc494425d77015c19579b9a9fa950e532b2  terraform-provider-dummy_3.2.1_darwin_amd64.zip
+`)
+
+	mux, mockServerURL := newMockServer()
+	mux.HandleFunc(shaSumsPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(200)
+		_, _ = w.Write(shaSumsData)
+	})
+
+	// create an unpacked provider package in the plugin directory, whose layout
+	// is the same as TF_PLUGIN_CACHE_DIR.
+	pluginDir := t.TempDir()
+	providerDir := filepath.Join(pluginDir, mockServerURL.Hostname(), "minamijoyo", "dummy", "3.2.1", "darwin_arm64")
+	if err := os.MkdirAll(providerDir, 0755); err != nil {
+		t.Fatalf("failed to create dir: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(providerDir, "terraform-provider-dummy_v3.2.1_x5"), []byte("dummy_3.2.1_darwin_arm64"), 0755); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+
+	cases := []struct {
+		desc          string
+		pluginDirs    []string
+		reqPluginDirs []string
+		arch          string
+		want          *ProviderDownloadResponse
+		ok            bool
+	}{
+		{
+			desc:       "found",
+			pluginDirs: []string{t.TempDir(), pluginDir},
+			arch:       "arm64",
+			want: &ProviderDownloadResponse{
+				filename:    "terraform-provider-dummy_3.2.1_darwin_arm64.zip",
+				h1Hash:      "h1:3323G20HW9PA9ONrL6CdQCdCFe6y94kXeOTprq+Zu+w=",
+				shaSumsData: shaSumsData,
+			},
+			ok: true,
+		},
+		{
+			desc:          "found in plugin dirs of request",
+			reqPluginDirs: []string{pluginDir},
+			arch:          "arm64",
+			want: &ProviderDownloadResponse{
+				filename:    "terraform-provider-dummy_3.2.1_darwin_arm64.zip",
+				h1Hash:      "h1:3323G20HW9PA9ONrL6CdQCdCFe6y94kXeOTprq+Zu+w=",
+				shaSumsData: shaSumsData,
+			},
+			ok: true,
+		},
+		{
+			desc:       "not found (fall back to download)",
+			pluginDirs: []string{pluginDir},
+			arch:       "amd64",
+			want:       nil,
+			ok:         false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := tfregistry.Config{}
+			client := newTestClient(mockServerURL, config)
+			client.pluginDirs = tc.pluginDirs
+			client.api = &mockTFRegistryClient{
+				metadataRes: &tfregistry.ProviderPackageMetadataResponse{
+					Filename:    "terraform-provider-dummy_3.2.1_darwin_" + tc.arch + ".zip",
+					DownloadURL: mockServerURL.String() + downloadPath,
+					SHASum:      "5622a0fd03420ed1fa83a1a6e90b65fbe34bc74c251b3b47048f14217e93b086",
+					SHASumsURL:  mockServerURL.String() + shaSumsPath,
+				},
+				err: nil,
+			}
+
+			req := &ProviderDownloadRequest{
+				Namespace:  "minamijoyo",
+				Type:       "dummy",
+				Version:    "3.2.1",
+				OS:         "darwin",
+				Arch:       tc.arch,
+				PluginDirs: tc.reqPluginDirs,
+			}
+
+			got, err := client.ProviderDownload(context.Background(), req)
+
+			if tc.ok && err != nil {
+				t.Fatalf("failed to call ProviderDownload: err = %s, req = %s", err, spew.Sdump(req))
+			}
+
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to fail, but success: req = %s, got = %s", spew.Sdump(req), spew.Sdump(got))
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got=%s, but want=%s", spew.Sdump(got), spew.Sdump(tc.want))
+			}
+		})
+	}
+}
+
 func TestProviderLockClientDownload(t *testing.T) {
 	subPath := "/terraform-provider-dummy/3.2.1/terraform-provider-dummy_3.2.1_darwin_arm64.zip"
 	cases := []struct {
@@ -100,11 +100,21 @@ func initCommands() map[string]cli.CommandFactory {
 				Meta: meta,
 			}, nil
 		},
+		"inventory": func() (cli.Command, error) {
+			return &command.InventoryCommand{
+				Meta: meta,
+			}, nil
+		},
 		"lock": func() (cli.Command, error) {
 			return &command.LockCommand{
 				Meta: meta,
 			}, nil
 		},
+		"outdated": func() (cli.Command, error) {
+			return &command.OutdatedCommand{
+				Meta: meta,
+			}, nil
+		},
 		"release": func() (cli.Command, error) {
 			return &command.ReleaseCommand{
 				Meta: meta,
@@ -4,6 +4,7 @@ import (
 	"log"
 	"maps"
 	"slices"
+	"strings"
 
 	version "github.com/hashicorp/go-version"
 	"github.com/minamijoyo/terraform-config-inspect/tfconfig"
@@ -20,6 +21,25 @@ type GlobalContext struct {
 
 	// option is a set of global parameters.
 	option Option
+
+	// results is a list of files updated in the current process.
+	results []Result
+
+	// warnings is a list of messages for updates which were skipped.
+	warnings []string
+
+	// failures is a list of files which failed to update.
+	// It is only recorded if the keepGoing option is set.
+	failures []Failure
+}
+
+// Failure is an error for a file which failed to update.
+type Failure struct {
+	// Filename is a path of the file which failed to update.
+	Filename string
+
+	// Err is an error which occurred while updating the file.
+	Err error
 }
 
 // NewGlobalContext returns a new instance of NewGlobalContext.
@@ -38,6 +58,73 @@ func NewGlobalContext(fs afero.Fs, o Option) (*GlobalContext, error) {
 	return gc, nil
 }
 
+// fork returns a new GlobalContext which shares the filesystem, the updater
+// and the option, but records results, warnings and failures separately. It is
+// used for processing modules concurrently. They are merged into the parent by
+// merge.
+func (gc *GlobalContext) fork() *GlobalContext {
+	return &GlobalContext{
+		fs:      gc.fs,
+		updater: gc.updater,
+		option:  gc.option,
+	}
+}
+
+// merge appends results, warnings and failures recorded in a forked
+// GlobalContext. A nil fork is ignored.
+func (gc *GlobalContext) merge(fork *GlobalContext) {
+	if fork == nil {
+		return
+	}
+	gc.results = append(gc.results, fork.results...)
+	gc.warnings = append(gc.warnings, fork.warnings...)
+	gc.failures = append(gc.failures, fork.failures...)
+}
+
+// Results returns a list of files updated in the current process.
+// The result is sorted alphabetically by filename.
+func (gc *GlobalContext) Results() []Result {
+	results := slices.Clone(gc.results)
+	slices.SortFunc(results, func(a, b Result) int {
+		return strings.Compare(a.Filename, b.Filename)
+	})
+	return results
+}
+
+// addResult records a result of updating a file.
+func (gc *GlobalContext) addResult(r Result) {
+	gc.results = append(gc.results, r)
+}
+
+// Warnings returns a list of messages for updates which were skipped.
+func (gc *GlobalContext) Warnings() []string {
+	return slices.Clone(gc.warnings)
+}
+
+// addWarning records a message for an update which was skipped.
+func (gc *GlobalContext) addWarning(msg string) {
+	log.Printf("[WARN] %s", msg)
+	gc.warnings = append(gc.warnings, msg)
+}
+
+// Failures returns a list of files which failed to update in the order of
+// the walk.
+func (gc *GlobalContext) Failures() []Failure {
+	return slices.Clone(gc.failures)
+}
+
+// keepGoing records an error for a given file as a failure and returns nil
+// if the keepGoing option is set. Otherwise, it returns the error as it is.
+func (gc *GlobalContext) keepGoing(filename string, err error) error {
+	if err == nil || !gc.option.keepGoing {
+		return err
+	}
+
+	log.Printf("[ERROR] failed to update file: %s: %s", filename, err)
+	gc.failures = append(gc.failures, Failure{Filename: filename, Err: err})
+	return nil
+}
+
 // ModuleContext is information shared across files within a directory.
 type ModuleContext struct {
 	// gc is a pointer to delegate some implementations to GlobalContext.
@@ -51,6 +138,14 @@ type ModuleContext struct {
 	// multiple constraints. The meaning depends on the use case and is therefore
 	// lazily evaluated.
 	requiredProviders map[string]*tfconfig.ProviderRequirement
+
+	// moduleCalls is a list of child modules called from the module.
+	// It is nil if the module failed to load.
+	moduleCalls map[string]*tfconfig.ModuleCall
+
+	// changes is a list of changes recorded by the updater for the file
+	// currently being updated. Files within a module are updated one by one.
+	changes []Change
 }
 
 // SelectedProvider is the source address and version of the provider, as
@@ -60,7 +155,14 @@ type SelectedProvider struct {
 	Source string
 
 	// version is a version of the provider.
+	// It is empty if the version constraints are not pinned to a specific
+	// version. In this case, the version needs to be resolved against the list
+	// of available versions.
 	Version string
+
+	// constraints is the version constraints of the provider normalized in the
+	// same format as the dependency lock file.
+	Constraints string
 }
 
 // aferoToTfconfigFS converts afero.Fs to tfconfig.FS.
@@ -82,6 +184,7 @@ func aferoToTfconfigFS(afs afero.Fs) tfconfig.FS {
 // The dir is a relative path to the module from the current working directory.
 func NewModuleContext(dir string, gc *GlobalContext) (*ModuleContext, error) {
 	requiredProviders := make(map[string]*tfconfig.ProviderRequirement)
+	var moduleCalls map[string]*tfconfig.ModuleCall
 	m, diags := tfconfig.LoadModuleFromFilesystem(aferoToTfconfigFS(gc.fs), dir)
 	if diags.HasErrors() {
 		// There is a known issue passing absolute paths to afero.IOFS results in
@@ -91,12 +194,14 @@ func NewModuleContext(dir string, gc *GlobalContext) (*ModuleContext, error) {
 		log.Printf("[DEBUG] failed to load module: dir = %s, err = %s", dir, diags)
 	} else {
 		requiredProviders = m.RequiredProviders
+		moduleCalls = m.ModuleCalls
 	}
 
 	c := &ModuleContext{
 		gc:                gc,
 		dir:               dir,
 		requiredProviders: requiredProviders,
+		moduleCalls:       moduleCalls,
 	}
 
 	return c, nil
@@ -122,12 +227,29 @@ func (mc *ModuleContext) Option() Option {
 	return mc.gc.option
 }
 
+// recordChange records a change of an attribute value made by the updater.
+// If the value doesn't change, it is not recorded.
+func (mc *ModuleContext) recordChange(c Change) {
+	if c.OldValue == c.NewValue {
+		return
+	}
+	mc.changes = append(mc.changes, c)
+}
+
+// takeChanges returns the recorded changes and clears them.
+func (mc *ModuleContext) takeChanges() []Change {
+	changes := mc.changes
+	mc.changes = nil
+	return changes
+}
+
 // SelectedProviders returns a list of providers inferred from version constraints.
 // The result is sorted alphabetically by source address.
-// Version constraints only support simple constants and not comparison
-// operators. Ignore what cannot be interpreted.
+// If version constraints are pinned to a specific version, the version is
+// selected. Otherwise, the version is left empty and the caller is responsible
+// for resolving the constraints. Ignore what cannot be interpreted.
 func (mc *ModuleContext) SelectedProviders() []SelectedProvider {
-	selected := make(map[string]string)
+	selected := make(map[string]SelectedProvider)
 	for _, p := range mc.requiredProviders {
 		if p.Source == "" {
 			// A source address with an empty string implies an unknown namespace prior to
@@ -138,9 +260,16 @@ func (mc *ModuleContext) SelectedProviders() []SelectedProvider {
 			continue
 		}
 
-		v := selectVersion(p.VersionConstraints)
+		s := SelectedProvider{
+			Source:      p.Source,
+			Version:     selectVersion(p.VersionConstraints),
+			Constraints: normalizeVersionConstraints(p.VersionConstraints),
+		}
+		if s.Constraints == "" {
+			s.Constraints = s.Version
+		}
 
-		if v == "" {
+		if s.Constraints == "" {
 			// Ignore if no version is specified.
 			log.Printf("[DEBUG] ModuleContext.SelectedProviders: ignore no version selected: %s", p.Source)
 			continue
@@ -148,7 +277,7 @@ func (mc *ModuleContext) SelectedProviders() []SelectedProvider {
 
 		// It is not possible to mix multiple provider versions in one module, so
 		// simply overwrite without taking duplicates into account
-		selected[p.Source] = v
+		selected[p.Source] = s
 	}
 
 	// Sort to get stable results
@@ -156,8 +285,7 @@ func (mc *ModuleContext) SelectedProviders() []SelectedProvider {
 
 	ret := []SelectedProvider{}
 	for _, k := range keys {
-		s := SelectedProvider{Source: k, Version: selected[k]}
-		ret = append(ret, s)
+		ret = append(ret, selected[k])
 	}
 	return ret
 }
@@ -165,8 +293,8 @@ func (mc *ModuleContext) SelectedProviders() []SelectedProvider {
 // selectVersion resolves version constraints and returns the version.
 // Note that it does not actually re-implement the resolution of version
 // constraints in terraform init. It is very simplified for the use we need.
-// Version constraints only support simple constants and not comparison
-// operators. Ignore what cannot be interpreted.
+// It only returns a version if the constraints are pinned to a specific
+// version with a simple constant. Ignore what cannot be interpreted.
 func selectVersion(constraints []string) string {
 	for _, c := range constraints {
 		v, err := version.NewVersion(c)
@@ -41,9 +41,9 @@ terraform {
 }
 `,
 			want: []SelectedProvider{
-				SelectedProvider{Source: "hashicorp/aws", Version: "5.4.0"},
-				SelectedProvider{Source: "hashicorp/null", Version: "3.1.1"},
-				SelectedProvider{Source: "integrations/github", Version: "4.28.0"},
+				SelectedProvider{Source: "hashicorp/aws", Version: "5.4.0", Constraints: "5.4.0"},
+				SelectedProvider{Source: "hashicorp/null", Version: "3.1.1", Constraints: "3.1.1"},
+				SelectedProvider{Source: "integrations/github", Version: "4.28.0", Constraints: "4.28.0"},
 			},
 		},
 		{
@@ -72,7 +72,7 @@ terraform {
 }
 `,
 			want: []SelectedProvider{
-				SelectedProvider{Source: "hashicorp/aws", Version: "5.4.0"},
+				SelectedProvider{Source: "hashicorp/aws", Version: "5.4.0", Constraints: "5.4.0"},
 			},
 		},
 		{
@@ -92,7 +92,7 @@ terraform {
 }
 `,
 			want: []SelectedProvider{
-				SelectedProvider{Source: "hashicorp/aws", Version: "5.4.0"},
+				SelectedProvider{Source: "hashicorp/aws", Version: "5.4.0", Constraints: "5.4.0"},
 			},
 		},
 		{
@@ -112,7 +112,7 @@ provider "null" {
 }
 `,
 			want: []SelectedProvider{
-				SelectedProvider{Source: "hashicorp/aws", Version: "5.4.0"},
+				SelectedProvider{Source: "hashicorp/aws", Version: "5.4.0", Constraints: "5.4.0"},
 			},
 		},
 		{
@@ -133,7 +133,30 @@ terraform {
 }
 `,
 			want: []SelectedProvider{
-				SelectedProvider{Source: "hashicorp/aws", Version: "5.4.0"},
+				SelectedProvider{Source: "hashicorp/aws", Version: "5.4.0", Constraints: "5.4.0"},
+				SelectedProvider{Source: "hashicorp/null", Version: "", Constraints: "> 3.0.0"},
+			},
+		},
+		{
+			desc: "multiple version constraints",
+			src: `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = ">= 4.0, < 6.0"
+    }
+
+    null = {
+      source  = "hashicorp/null"
+      version = "~> 3.2"
+    }
+  }
+}
+`,
+			want: []SelectedProvider{
+				SelectedProvider{Source: "hashicorp/aws", Version: "", Constraints: ">= 4.0.0, < 6.0.0"},
+				SelectedProvider{Source: "hashicorp/null", Version: "", Constraints: "~> 3.2"},
 			},
 		},
 	}
@@ -334,7 +357,7 @@ terraform {
 `,
 			filename: "main.tf",
 			want: []SelectedProvider{
-				{Source: "hashicorp/aws", Version: "5.4.0"},
+				{Source: "hashicorp/aws", Version: "5.4.0", Constraints: "5.4.0"},
 			},
 		},
 		{
@@ -353,7 +376,7 @@ terraform {
 `,
 			filename: "main.tofu",
 			want: []SelectedProvider{
-				{Source: "hashicorp/aws", Version: "5.4.0"},
+				{Source: "hashicorp/aws", Version: "5.4.0", Constraints: "5.4.0"},
 			},
 		},
 	}
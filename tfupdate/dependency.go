@@ -0,0 +1,366 @@
+package tfupdate
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/afero"
+)
+
+// Dependency is a version constraint of a dependency found in a file.
+type Dependency struct {
+	// Filename is a path of the file where the dependency is found.
+	Filename string `json:"filename"`
+
+	// Type is a type of the dependency. Valid values are as follows:
+	// - terraform
+	// - opentofu
+	// - provider
+	// - module
+	Type string `json:"type"`
+
+	// Name is a local name of the provider or a name label of the module block.
+	// It is empty for terraform, opentofu and the module in terragrunt.hcl.
+	Name string `json:"name,omitempty"`
+
+	// Source is a source address of the provider or a source of the module
+	// without a version reference.
+	// It is empty if the provider doesn't have an explicit source address.
+	Source string `json:"source,omitempty"`
+
+	// Version is a version constraint.
+	Version string `json:"version,omitempty"`
+
+	// Ref is a version number in the module source such as ?ref=v1.2.3.
+	Ref string `json:"ref,omitempty"`
+
+	// Syntax is a syntax of the provider version constraint. Valid values are
+	// as follows:
+	// - object: An object in the required_providers block.
+	// - legacy_string: A string in the required_providers block.
+	// - legacy_provider_block: A version attribute in the provider block.
+	// It is empty for other than providers.
+	Syntax string `json:"syntax,omitempty"`
+}
+
+// CollectDependencies returns a list of dependencies found in a given file or
+// directory. It walks directories in the same way as UpdateFileOrDir without
+// writing any files.
+func CollectDependencies(fs afero.Fs, o Option, path string) ([]Dependency, error) {
+	// We only inspect files here, so an updater is not needed.
+	gc := &GlobalContext{
+		fs:     fs,
+		option: o,
+	}
+
+	deps := []Dependency{}
+	err := walkFileOrDir(gc, path, nil, func(mc *ModuleContext, filename string) error {
+		d, err := collectFileDependencies(mc, filename)
+		if err != nil {
+			return err
+		}
+		deps = append(deps, d...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deps, nil
+}
+
+// collectFileDependencies returns a list of dependencies found in a single file.
+func collectFileDependencies(mc *ModuleContext, filename string) ([]Dependency, error) {
+	if filepath.Base(filename) == ".terraform.lock.hcl" {
+		// The lock file is not a place to declare dependencies.
+		return nil, nil
+	}
+
+	r, err := mc.FS().Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %s", err)
+	}
+	defer r.Close()
+
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %s", err)
+	}
+
+	if isJSONFile(filename) {
+		return collectJSONDependencies(mc, filename, input)
+	}
+
+	if isVersionManagerFile(filename) {
+		return collectVersionManagerDependencies(filename, input), nil
+	}
+
+	f, err := safeParseConfig(input, filename, hcl.Pos{Line: 1, Column: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	deps := []Dependency{}
+	coreType := coreDependencyType(filename)
+
+	for _, tf := range allMatchingBlocks(f.Body(), "terraform", []string{}) {
+		if attr := tf.Body().GetAttribute("required_version"); attr != nil {
+			deps = append(deps, Dependency{
+				Filename: filename,
+				Type:     coreType,
+				Version:  getAttributeValueAsUnquotedString(attr),
+			})
+		}
+
+		if isTerragruntFile(filename) {
+			// The terraform block in the Terragrunt configuration file has a source
+			// of the module to be deployed.
+			if s := tf.Body().GetAttribute("source"); s != nil {
+				if source, ref := parseModuleSource(s); source != "" {
+					deps = append(deps, Dependency{
+						Filename: filename,
+						Type:     "module",
+						Source:   source,
+						Ref:      ref,
+					})
+				}
+			}
+			continue
+		}
+
+		p := tf.Body().FirstMatchingBlock("required_providers", []string{})
+		if p == nil {
+			continue
+		}
+
+		// Sort to get stable results
+		names := []string{}
+		for name := range p.Body().Attributes() {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		for _, name := range names {
+			d, err := collectRequiredProvider(mc, filename, p, name)
+			if err != nil {
+				return nil, err
+			}
+			deps = append(deps, d)
+		}
+	}
+
+	for _, p := range allMatchingBlocksByType(f.Body(), "provider") {
+		if len(p.Labels()) == 0 {
+			continue
+		}
+		if attr := p.Body().GetAttribute("version"); attr != nil {
+			name := p.Labels()[0]
+			deps = append(deps, Dependency{
+				Filename: filename,
+				Type:     "provider",
+				Name:     name,
+				Source:   mc.requiredProviderSource(name),
+				Version:  getAttributeValueAsUnquotedString(attr),
+				Syntax:   "legacy_provider_block",
+			})
+		}
+	}
+
+	for _, m := range allMatchingBlocksByType(f.Body(), "module") {
+		s := m.Body().GetAttribute("source")
+		if s == nil || len(m.Labels()) == 0 {
+			continue
+		}
+		source, ref := parseModuleSource(s)
+		if source == "" {
+			// The source is not a string literal.
+			continue
+		}
+
+		d := Dependency{
+			Filename: filename,
+			Type:     "module",
+			Name:     m.Labels()[0],
+			Source:   source,
+			Ref:      ref,
+		}
+		if v := m.Body().GetAttribute("version"); v != nil {
+			d.Version = getAttributeValueAsUnquotedString(v)
+		}
+		deps = append(deps, d)
+	}
+
+	return deps, nil
+}
+
+// collectJSONDependencies returns a list of dependencies found in a single
+// file in the JSON syntax.
+func collectJSONDependencies(mc *ModuleContext, filename string, input []byte) ([]Dependency, error) {
+	f, err := parseJSONConfig(input, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := []Dependency{}
+	coreType := coreDependencyType(filename)
+
+	for _, tf := range allMatchingJSONBlocks(f.body, "terraform") {
+		if attr := f.getString(tf, "required_version"); attr != nil {
+			deps = append(deps, Dependency{
+				Filename: filename,
+				Type:     coreType,
+				Version:  attr.str,
+			})
+		}
+
+		for _, p := range allMatchingJSONBlocks(tf, "required_providers") {
+			for _, attr := range allJSONAttributes(p) {
+				d := Dependency{
+					Filename: filename,
+					Type:     "provider",
+					Name:     attr.Name,
+				}
+				if v := f.stringValue(attr.Expr); v != nil {
+					d.Syntax = "legacy_string"
+					d.Source = mc.requiredProviderSource(attr.Name)
+					d.Version = v.str
+				} else if isJSONObject(attr.Expr) {
+					d.Syntax = "object"
+					if s := f.getMemberString(attr.Expr, "source"); s != nil {
+						d.Source = s.str
+					}
+					if v := f.getMemberString(attr.Expr, "version"); v != nil {
+						d.Version = v.str
+					}
+				} else {
+					continue
+				}
+				deps = append(deps, d)
+			}
+		}
+	}
+
+	for _, p := range allMatchingJSONLabeledBlocks(f.body, "provider") {
+		if attr := f.getString(p.body, "version"); attr != nil {
+			deps = append(deps, Dependency{
+				Filename: filename,
+				Type:     "provider",
+				Name:     p.label,
+				Source:   mc.requiredProviderSource(p.label),
+				Version:  attr.str,
+				Syntax:   "legacy_provider_block",
+			})
+		}
+	}
+
+	for _, m := range allMatchingJSONLabeledBlocks(f.body, "module") {
+		s := f.getString(m.body, "source")
+		if s == nil {
+			continue
+		}
+		source, ref := parseModuleSourceString(s.str)
+		d := Dependency{
+			Filename: filename,
+			Type:     "module",
+			Name:     m.label,
+			Source:   source,
+			Ref:      ref,
+		}
+		if v := f.getString(m.body, "version"); v != nil {
+			d.Version = v.str
+		}
+		deps = append(deps, d)
+	}
+
+	return deps, nil
+}
+
+// collectVersionManagerDependencies returns a list of dependencies found in a
+// version file of version managers such as .terraform-version.
+func collectVersionManagerDependencies(filename string, input []byte) []Dependency {
+	deps := []Dependency{}
+
+	if tool, ok := versionFileTools[filepath.Base(filename)]; ok {
+		if v := strings.TrimSpace(string(input)); v != "" {
+			deps = append(deps, Dependency{
+				Filename: filename,
+				Type:     tool,
+				Version:  v,
+			})
+		}
+		return deps
+	}
+
+	for _, line := range strings.Split(string(input), "\n") {
+		content, _, _ := strings.Cut(line, "#")
+		fields := strings.Fields(content)
+		if len(fields) < 2 || (fields[0] != "terraform" && fields[0] != "opentofu") {
+			continue
+		}
+		deps = append(deps, Dependency{
+			Filename: filename,
+			Type:     fields[0],
+			Version:  fields[1],
+		})
+	}
+
+	return deps
+}
+
+// coreDependencyType returns a type of the core dependency for a given
+// filename. It is opentofu for .tofu files, otherwise terraform.
+func coreDependencyType(filename string) string {
+	if strings.HasSuffix(filename, ".tofu") || strings.HasSuffix(filename, ".tofu.json") {
+		return "opentofu"
+	}
+	return "terraform"
+}
+
+// collectRequiredProvider returns a dependency for a given local name in the
+// required_providers block.
+func collectRequiredProvider(mc *ModuleContext, filename string, p *hclwrite.Block, name string) (Dependency, error) {
+	d := Dependency{
+		Filename: filename,
+		Type:     "provider",
+		Name:     name,
+	}
+
+	hclAttr, err := getHCLNativeAttribute(p.Body(), name)
+	if err != nil {
+		return d, err
+	}
+
+	// There are some variations on the syntax of required_providers.
+	// See also ProviderUpdater.updateTerraformBlock.
+	if expr, err := hclAttr.Expr.Value(nil); err == nil && expr.Type().IsPrimitiveType() {
+		// legacy string syntax
+		d.Syntax = "legacy_string"
+		d.Source = mc.requiredProviderSource(name)
+		d.Version = getAttributeValueAsUnquotedString(p.Body().GetAttribute(name))
+		return d, nil
+	}
+
+	// object syntax
+	d.Syntax = "object"
+	if d.Source, err = detectStringInObject(hclAttr, "source"); err != nil {
+		return d, err
+	}
+	if d.Version, err = detectVersionInObject(hclAttr); err != nil {
+		return d, err
+	}
+	return d, nil
+}
+
+// requiredProviderSource returns a source address for a given local name in
+// the module. If not found, it returns an empty string.
+func (mc *ModuleContext) requiredProviderSource(name string) string {
+	if p, ok := mc.requiredProviders[name]; ok {
+		return p.Source
+	}
+	return ""
+}
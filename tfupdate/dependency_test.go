@@ -0,0 +1,149 @@
+package tfupdate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestCollectDependencies(t *testing.T) {
+	files := map[string]string{
+		"a/main.tf": `
+terraform {
+  required_version = "1.5.0"
+
+  required_providers {
+    null = "3.2.1"
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+
+      configuration_aliases = [
+        aws.primary,
+      ]
+    }
+  }
+}
+
+provider "null" {
+  version = "3.2.1"
+}
+
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.1.0"
+}
+
+module "git" {
+  source = "git::https://example.com/vpc.git?ref=v1.2.3"
+}
+
+module "local" {
+  source = "./local"
+}
+`,
+		"a/.terraform.lock.hcl": `
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.4.0"
+  constraints = "5.4.0"
+}
+`,
+		"a/b/main.tofu": `
+terraform {
+  required_version = "1.6.0"
+}
+`,
+		"a/c/main.tf.json": `{
+  "terraform": {
+    "required_version": "1.5.0",
+    "required_providers": {
+      "aws": {"source": "hashicorp/aws", "version": "~> 5.0"}
+    }
+  },
+  "module": {
+    "vpc": {"source": "terraform-aws-modules/vpc/aws", "version": "5.1.0"}
+  }
+}
+`,
+		"a/d/terragrunt.hcl": `
+terraform {
+  source = "git::https://example.com/modules.git//vpc?ref=v1.2.3"
+}
+`,
+		"a/.terraform-version": "1.5.7\n",
+		"a/b/.tool-versions": `nodejs 20.11.0
+opentofu 1.6.0 # comment
+`,
+		"a/.terraform/main.tf": `
+terraform {
+  required_version = "1.4.0"
+}
+`,
+	}
+
+	cases := []struct {
+		desc      string
+		path      string
+		recursive bool
+		want      []Dependency
+	}{
+		{
+			desc:      "recursive",
+			path:      "a",
+			recursive: true,
+			want: []Dependency{
+				{Filename: "a/.terraform-version", Type: "terraform", Version: "1.5.7"},
+				{Filename: "a/b/.tool-versions", Type: "opentofu", Version: "1.6.0"},
+				{Filename: "a/b/main.tofu", Type: "opentofu", Version: "1.6.0"},
+				{Filename: "a/c/main.tf.json", Type: "terraform", Version: "1.5.0"},
+				{Filename: "a/c/main.tf.json", Type: "provider", Name: "aws", Source: "hashicorp/aws", Version: "~> 5.0", Syntax: "object"},
+				{Filename: "a/c/main.tf.json", Type: "module", Name: "vpc", Source: "terraform-aws-modules/vpc/aws", Version: "5.1.0"},
+				{Filename: "a/d/terragrunt.hcl", Type: "module", Source: "git::https://example.com/modules.git//vpc", Ref: "1.2.3"},
+				{Filename: "a/main.tf", Type: "terraform", Version: "1.5.0"},
+				{Filename: "a/main.tf", Type: "provider", Name: "aws", Source: "hashicorp/aws", Version: "~> 5.0", Syntax: "object"},
+				{Filename: "a/main.tf", Type: "provider", Name: "null", Version: "3.2.1", Syntax: "legacy_string"},
+				{Filename: "a/main.tf", Type: "provider", Name: "null", Version: "3.2.1", Syntax: "legacy_provider_block"},
+				{Filename: "a/main.tf", Type: "module", Name: "vpc", Source: "terraform-aws-modules/vpc/aws", Version: "5.1.0"},
+				{Filename: "a/main.tf", Type: "module", Name: "git", Source: "git::https://example.com/vpc.git", Ref: "1.2.3"},
+				{Filename: "a/main.tf", Type: "module", Name: "local", Source: "./local"},
+			},
+		},
+		{
+			desc:      "file",
+			path:      "a/b/main.tofu",
+			recursive: false,
+			want: []Dependency{
+				{Filename: "a/b/main.tofu", Type: "opentofu", Version: "1.6.0"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for filename, src := range files {
+				if err := fs.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := afero.WriteFile(fs, filename, []byte(src), 0644); err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			o := Option{
+				recursive: tc.recursive,
+			}
+			got, err := CollectDependencies(fs, o, tc.path)
+			if err != nil {
+				t.Fatalf("CollectDependencies() returns an unexpected error: %s", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("CollectDependencies() returns %#v, but want = %#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package tfupdate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is the number of unchanged lines shown around changes.
+const diffContextLines = 3
+
+// diffOpKind is a kind of edit operation.
+type diffOpKind int
+
+const (
+	diffOpEqual diffOpKind = iota
+	diffOpDelete
+	diffOpInsert
+)
+
+// diffOp is an edit operation for a single line.
+type diffOp struct {
+	kind diffOpKind
+	line string
+}
+
+// Diff returns a unified diff between the original and updated contents of
+// the given file. If there is no difference, it returns an empty string.
+func Diff(filename string, original []byte, updated []byte) string {
+	ops := diffLines(splitLines(string(original)), splitLines(string(updated)))
+
+	var sb strings.Builder
+	for _, h := range buildHunks(ops) {
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", filename, filename)
+		}
+		sb.WriteString(h)
+	}
+
+	return sb.String()
+}
+
+// splitLines splits a string into lines. Each line keeps its trailing newline
+// so that we can tell whether the last line ends with a newline.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes the shortest edit script to convert a into b with the
+// Myers' difference algorithm.
+func diffLines(a []string, b []string) []diffOp {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// Record a snapshot of v at the beginning of each step for backtracking.
+	trace := [][]int{}
+	found := false
+	for d := 0; d <= maxD && !found; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	// Backtrack from the end to build the edit script in reverse order.
+	reversed := []diffOp{}
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, diffOp{kind: diffOpEqual, line: a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, diffOp{kind: diffOpInsert, line: b[y-1]})
+				y--
+			} else {
+				reversed = append(reversed, diffOp{kind: diffOpDelete, line: a[x-1]})
+				x--
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		ops = append(ops, reversed[i])
+	}
+	return ops
+}
+
+// buildHunks groups the edit script into hunks in the unified format.
+func buildHunks(ops []diffOp) []string {
+	// Calculate line numbers before each operation.
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		aPos[i+1] = aPos[i]
+		bPos[i+1] = bPos[i]
+		if op.kind != diffOpInsert {
+			aPos[i+1]++
+		}
+		if op.kind != diffOpDelete {
+			bPos[i+1]++
+		}
+	}
+
+	hunks := []string{}
+	i := 0
+	for i < len(ops) {
+		if ops[i].kind == diffOpEqual {
+			i++
+			continue
+		}
+
+		start := max(0, i-diffContextLines)
+		last := i
+		j := i
+		for j < len(ops) {
+			if ops[j].kind != diffOpEqual {
+				last = j
+				j++
+				continue
+			}
+			// Merge hunks if the next change is close enough.
+			k := j
+			for k < len(ops) && ops[k].kind == diffOpEqual {
+				k++
+			}
+			if k < len(ops) && k-j <= 2*diffContextLines {
+				j = k
+				continue
+			}
+			break
+		}
+		end := min(len(ops), last+1+diffContextLines)
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n",
+			hunkRange(aPos[start], aPos[end]-aPos[start]),
+			hunkRange(bPos[start], bPos[end]-bPos[start]),
+		)
+		for _, op := range ops[start:end] {
+			switch op.kind {
+			case diffOpEqual:
+				sb.WriteString(" ")
+			case diffOpDelete:
+				sb.WriteString("-")
+			case diffOpInsert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		hunks = append(hunks, sb.String())
+
+		i = end
+	}
+
+	return hunks
+}
+
+// hunkRange formats a range of lines in a hunk header.
+// The start is a 0-based index, but line numbers in a hunk header are 1-based.
+// An empty range refers to the line just before it.
+func hunkRange(start int, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
@@ -0,0 +1,191 @@
+package tfupdate
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		desc     string
+		filename string
+		original string
+		updated  string
+		want     string
+	}{
+		{
+			desc:     "no change",
+			filename: "main.tf",
+			original: `terraform {
+  required_version = "0.12.6"
+}
+`,
+			updated: `terraform {
+  required_version = "0.12.6"
+}
+`,
+			want: "",
+		},
+		{
+			desc:     "simple",
+			filename: "main.tf",
+			original: `terraform {
+  required_version = "0.12.6"
+}
+`,
+			updated: `terraform {
+  required_version = "0.12.7"
+}
+`,
+			want: `--- main.tf
++++ main.tf
+@@ -1,3 +1,3 @@
+ terraform {
+-  required_version = "0.12.6"
++  required_version = "0.12.7"
+ }
+`,
+		},
+		{
+			desc:     "multiple hunks",
+			filename: "a/main.tf",
+			original: `terraform {
+  required_version = "0.12.6"
+}
+
+locals {
+  a = 1
+  b = 2
+  c = 3
+  d = 4
+}
+
+provider "aws" {
+  version = "2.11.0"
+}
+`,
+			updated: `terraform {
+  required_version = "0.12.7"
+}
+
+locals {
+  a = 1
+  b = 2
+  c = 3
+  d = 4
+}
+
+provider "aws" {
+  version = "2.23.0"
+}
+`,
+			want: `--- a/main.tf
++++ a/main.tf
+@@ -1,5 +1,5 @@
+ terraform {
+-  required_version = "0.12.6"
++  required_version = "0.12.7"
+ }
+ 
+ locals {
+@@ -10,5 +10,5 @@
+ }
+ 
+ provider "aws" {
+-  version = "2.11.0"
++  version = "2.23.0"
+ }
+`,
+		},
+		{
+			desc:     "merge close hunks",
+			filename: "main.tf",
+			original: `a
+b
+c
+d
+e
+f
+g
+`,
+			updated: `a
+B
+c
+d
+e
+F
+g
+`,
+			want: `--- main.tf
++++ main.tf
+@@ -1,7 +1,7 @@
+ a
+-b
++B
+ c
+ d
+ e
+-f
++F
+ g
+`,
+		},
+		{
+			desc:     "insert and delete lines",
+			filename: "main.tf",
+			original: `a
+b
+c
+`,
+			updated: `a
+c
+d
+`,
+			want: `--- main.tf
++++ main.tf
+@@ -1,3 +1,3 @@
+ a
+-b
+ c
++d
+`,
+		},
+		{
+			desc:     "empty original",
+			filename: "main.tf",
+			original: ``,
+			updated: `a
+`,
+			want: `--- main.tf
++++ main.tf
+@@ -0,0 +1 @@
++a
+`,
+		},
+		{
+			desc:     "no newline at end of file",
+			filename: "main.tf",
+			original: `a
+b`,
+			updated: `a
+c`,
+			want: `--- main.tf
++++ main.tf
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := Diff(tc.filename, []byte(tc.original), []byte(tc.updated))
+			if got != tc.want {
+				t.Errorf("Diff() returns:\n%s\nbut want:\n%s", got, tc.want)
+			}
+		})
+	}
+}
@@ -3,16 +3,39 @@ package tfupdate
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/spf13/afero"
 )
 
+// WalkPolicy is a set of parameters to walk directories.
+type WalkPolicy struct {
+	// Recursive is a flag to walk directories recursively.
+	Recursive bool
+
+	// IgnorePaths is a list of regular expressions for paths to ignore.
+	IgnorePaths []string
+
+	// Jobs is the number of modules processed concurrently in a recursive
+	// mode. If less than 2, modules are processed one by one and the first
+	// error aborts the walk. Otherwise, an error in a module doesn't stop
+	// processing the others, and all errors are returned at the end.
+	Jobs int
+
+	// KeepGoing is a flag to continue updating other files when a file fails
+	// to update. The errors are recorded as failures in the GlobalContext
+	// instead of being returned.
+	KeepGoing bool
+}
+
 // UpdateFile updates version constraints in a single file.
 // We use an afero filesystem here for testing.
 func UpdateFile(ctx context.Context, mc *ModuleContext, filename string) error {
@@ -23,24 +46,61 @@ func UpdateFile(ctx context.Context, mc *ModuleContext, filename string) error {
 	}
 	defer r.Close()
 
+	return updateFile(ctx, mc, filename, r, false)
+}
+
+// updateFile updates version constraints in contents read from io.Reader and
+// writes them to a given filename if changed. If a create flag is true, the
+// file doesn't need to exist and the result records empty original contents.
+func updateFile(ctx context.Context, mc *ModuleContext, filename string, r io.Reader, create bool) error {
+	input := &bytes.Buffer{}
 	w := &bytes.Buffer{}
-	isUpdated, err := UpdateHCL(ctx, mc, r, w, filename)
+	update := UpdateHCL
+	switch {
+	case isJSONFile(filename):
+		update = UpdateJSON
+	case isVersionManagerFile(filename):
+		update = UpdateVersionManagerFile
+	}
+	isUpdated, err := update(ctx, mc, io.TeeReader(r, input), w, filename)
+	changes := mc.takeChanges()
 	if err != nil {
 		return err
 	}
 
 	// Write contents back to source file if changed.
 	if isUpdated {
-		log.Printf("[INFO] update file: %s", filename)
 		updated := w.Bytes()
-		// We should be able to choose whether to format output or not.
-		// However, the current implementation of (*hclwrite.Body).SetAttributeValue()
-		// does not seem to preserve an original SpaceBefore value of attribute.
-		// So, we need to format output here.
-		result := hclwrite.Format(updated)
+		// Updaters rewrite only the changed tokens as much as possible, but for
+		// backward compatibility, we format the whole file unless the noFormat
+		// option is set. Note that the lock file is always formatted because it
+		// is maintained by tools and a new provider block appended to it is not
+		// aligned. Files other than HCL are never formatted.
+		result := updated
+		if isHCLFile(filename) && (!mc.Option().noFormat || filepath.Base(filename) == ".terraform.lock.hcl") {
+			result = hclwrite.Format(updated)
+		}
+		if bytes.Equal(input.Bytes(), result) {
+			// The formatted result may be the same as the original.
+			// Nothing to do in this case.
+			return nil
+		}
+
+		log.Printf("[INFO] update file: %s", filename)
 		if err = afero.WriteFile(mc.FS(), filename, result, os.ModePerm); err != nil {
 			return fmt.Errorf("failed to write file: %s", err)
 		}
+
+		original := input.Bytes()
+		if create {
+			original = nil
+		}
+		mc.GlobalContext().addResult(Result{
+			Filename: filename,
+			Original: original,
+			Updated:  result,
+			Changes:  changes,
+		})
 	}
 
 	return nil
@@ -51,13 +111,101 @@ func UpdateFile(ctx context.Context, mc *ModuleContext, filename string) error {
 // skip hidden directories such as .terraform or .git.
 // It also skips unsupported file type.
 func UpdateDir(ctx context.Context, current *ModuleContext, dirname string) error {
+	return walkDir(current, dirname, func(mc *ModuleContext, filename string) error {
+		return UpdateFile(ctx, mc, filename)
+	})
+}
+
+// UpdateFileOrDir updates version constraints in a given file or directory.
+// If the keepGoing option is set, errors for files are recorded as failures in
+// the GlobalContext and the rest of files are still updated.
+// If the createLockFile option is set, a dependency lock file is created in
+// each root module before updating files in it.
+func UpdateFileOrDir(ctx context.Context, gc *GlobalContext, path string) error {
+	walk := walkFileOrDir
+	if gc.option.jobs > 1 {
+		walk = walkFileOrDirConcurrently
+	}
+
+	var dirFn func(mc *ModuleContext) error
+	if gc.option.createLockFile {
+		childDirs, err := collectChildModuleDirs(gc, path)
+		if err != nil {
+			return err
+		}
+		dirFn = func(mc *ModuleContext) error {
+			if childDirs[mc.dir] {
+				// A child module doesn't need a lock file.
+				return nil
+			}
+			return createLockFile(ctx, mc)
+		}
+	}
+
+	return walk(gc, path, dirFn, func(mc *ModuleContext, filename string) error {
+		return mc.gc.keepGoing(filename, UpdateFile(ctx, mc, filename))
+	})
+}
+
+// walkDir calls fn for each supported file in a given directory.
+// If a recursive flag is true, it walks directories recursively.
+// skip hidden directories such as .terraform or .git.
+// It also skips unsupported file type.
+func walkDir(current *ModuleContext, dirname string, fn func(mc *ModuleContext, filename string) error) error {
+	return walkModuleDir(current, dirname, nil, fn)
+}
+
+// walkModuleDir is the same as walkDir, but also calls dirFn for each module
+// before files in it. Either of dirFn and fileFn may be nil.
+// The current is a module context for the given directory, and a new module
+// context is created for each subdirectory.
+func walkModuleDir(current *ModuleContext, dirname string, dirFn func(mc *ModuleContext) error, fileFn func(mc *ModuleContext, filename string) error) error {
+	modules := make(map[string]*ModuleContext)
+	return walkTree(current.gc, dirname,
+		func(dir string) error {
+			mc := current
+			if dir != dirname {
+				var err error
+				mc, err = NewModuleContext(dir, current.GlobalContext())
+				if err != nil {
+					return err
+				}
+			}
+			modules[dir] = mc
+
+			if dirFn == nil {
+				return nil
+			}
+			return dirFn(mc)
+		},
+		func(dir string, filename string) error {
+			if fileFn == nil {
+				return nil
+			}
+			return fileFn(modules[dir], filename)
+		},
+	)
+}
+
+// walkTree walks a given directory and calls dirFn for each module directory
+// and fileFn for each supported file in it. The dirFn is called before files
+// and subdirectories in the directory. This is the only place which defines
+// the rules of traversal, so other walk functions should be built on it.
+// If a recursive flag is true, it walks directories recursively.
+// skip hidden directories such as .terraform or .git.
+// It also skips unsupported file type.
+func walkTree(gc *GlobalContext, dirname string, dirFn func(dir string) error, fileFn func(dir string, filename string) error) error {
 	log.Printf("[DEBUG] check dir: %s", dirname)
-	option := current.Option()
-	dir, err := afero.ReadDir(current.FS(), dirname)
+	option := gc.option
+	dir, err := afero.ReadDir(gc.fs, dirname)
 	if err != nil {
 		return fmt.Errorf("failed to open dir: %s", err)
 	}
 
+	if err := dirFn(dirname); err != nil {
+		return err
+	}
+
 	for _, entry := range dir {
 		path := filepath.Join(dirname, entry.Name())
 
@@ -78,12 +226,7 @@ func UpdateDir(ctx context.Context, current *ModuleContext, dirname string) erro
 				continue
 			}
 
-			child, err := NewModuleContext(path, current.GlobalContext())
-			if err != nil {
-				return err
-			}
-
-			err = UpdateDir(ctx, child, path)
+			err := walkTree(gc, path, dirFn, fileFn)
 			if err != nil {
 				return err
 			}
@@ -92,14 +235,12 @@ func UpdateDir(ctx context.Context, current *ModuleContext, dirname string) erro
 		}
 
 		// if an entry is a file
-		// nolint:staticcheck // QF1001: could apply De Morgan's law
-		// Ignore it in favor of subjective readability.
-		if !(filepath.Ext(entry.Name()) == ".tf" || filepath.Ext(entry.Name()) == ".tofu" || entry.Name() == ".terraform.lock.hcl") {
+		if !isSupportedFile(entry.Name()) {
 			// skip unsupported file type
 			continue
 		}
 
-		err := UpdateFile(ctx, current, path)
+		err := fileFn(dirname, path)
 		if err != nil {
 			return err
 		}
@@ -107,8 +248,12 @@ func UpdateDir(ctx context.Context, current *ModuleContext, dirname string) erro
 	return nil
 }
 
-// UpdateFileOrDir updates version constraints in a given file or directory.
-func UpdateFileOrDir(ctx context.Context, gc *GlobalContext, path string) error {
+// walkFileOrDir calls dirFn for each module and fileFn for a given file or
+// each supported file in a given directory. If the path is a file, the
+// directory containing it is treated as a module. Either of dirFn and fileFn
+// may be nil. Calling it only with dirFn is useful for inspecting modules
+// before updating.
+func walkFileOrDir(gc *GlobalContext, path string, dirFn func(mc *ModuleContext) error, fileFn func(mc *ModuleContext, filename string) error) error {
 	isDir, err := afero.IsDir(gc.fs, path)
 	if err != nil {
 		return fmt.Errorf("failed to open path: %s", err)
@@ -120,7 +265,7 @@ func UpdateFileOrDir(ctx context.Context, gc *GlobalContext, path string) error
 		if err != nil {
 			return err
 		}
-		return UpdateDir(ctx, mc, path)
+		return walkModuleDir(mc, path, dirFn, fileFn)
 	}
 
 	// if an entry is a file
@@ -131,7 +276,144 @@ func UpdateFileOrDir(ctx context.Context, gc *GlobalContext, path string) error
 	if err != nil {
 		return err
 	}
+
+	if dirFn != nil {
+		if err := dirFn(mc); err != nil {
+			return err
+		}
+	}
+
+	if fileFn == nil {
+		return nil
+	}
 	// When the filename is intentionally specified,
 	// we should not ignore it by its extension as much as possible.
-	return UpdateFile(ctx, mc, path)
+	return fileFn(mc, path)
+}
+
+// walkFileOrDirConcurrently is the same as walkFileOrDir, but processes
+// modules concurrently. Files within a module are processed one by one with
+// a forked GlobalContext for each step, and the forked contexts are merged in
+// the order of the walk, so that results and warnings are reported in the
+// same order as walkFileOrDir. Unlike walkFileOrDir, an error in a module
+// stops processing only the rest of the module, and all errors are returned
+// in the order of the walk.
+func walkFileOrDirConcurrently(gc *GlobalContext, path string, dirFn func(mc *ModuleContext) error, fileFn func(mc *ModuleContext, filename string) error) error {
+	isDir, err := afero.IsDir(gc.fs, path)
+	if err != nil {
+		return fmt.Errorf("failed to open path: %s", err)
+	}
+
+	if !isDir || !gc.option.recursive {
+		// Nothing to parallelize.
+		return walkFileOrDir(gc, path, dirFn, fileFn)
+	}
+
+	// List steps in the order of the walk without calling any callbacks.
+	dirs := []string{}
+	steps := []walkStep{}
+	modules := make(map[string]int)
+	err = walkTree(gc, path,
+		func(dir string) error {
+			modules[dir] = len(dirs)
+			dirs = append(dirs, dir)
+			steps = append(steps, walkStep{module: modules[dir]})
+			return nil
+		},
+		func(dir string, filename string) error {
+			steps = append(steps, walkStep{module: modules[dir], filename: filename})
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	moduleSteps := make([][]int, len(dirs))
+	for i, step := range steps {
+		moduleSteps[step.module] = append(moduleSteps[step.module], i)
+	}
+
+	forks := make([]*GlobalContext, len(steps))
+	errs := make([]error, len(steps))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for range min(gc.option.jobs, len(dirs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range queue {
+				// The module context is created at the first step of the module
+				// and shared by the rest of the steps.
+				var mc *ModuleContext
+				for _, i := range moduleSteps[m] {
+					forks[i] = gc.fork()
+					if mc == nil {
+						mc, errs[i] = NewModuleContext(dirs[m], forks[i])
+					}
+					if errs[i] == nil {
+						mc.gc = forks[i]
+						errs[i] = steps[i].run(mc, dirFn, fileFn)
+					}
+					if errs[i] != nil {
+						// Skip the rest of the module.
+						break
+					}
+				}
+			}
+		}()
+	}
+	for m := range dirs {
+		queue <- m
+	}
+	close(queue)
+	wg.Wait()
+
+	for i := range steps {
+		gc.merge(forks[i])
+	}
+
+	return errors.Join(errs...)
+}
+
+// walkStep is a step of walking a directory tree, which is a call of dirFn
+// or fileFn.
+type walkStep struct {
+	// module is an index of the module directory.
+	module int
+
+	// filename is a file to call fileFn. It is empty for a step to call dirFn.
+	filename string
+}
+
+// run calls dirFn or fileFn for the step. Either of them may be nil.
+func (s walkStep) run(mc *ModuleContext, dirFn func(mc *ModuleContext) error, fileFn func(mc *ModuleContext, filename string) error) error {
+	if s.filename == "" {
+		if dirFn == nil {
+			return nil
+		}
+		return dirFn(mc)
+	}
+
+	if fileFn == nil {
+		return nil
+	}
+	return fileFn(mc, s.filename)
+}
+
+// isSupportedFile returns true if a given filename is a supported file type.
+func isSupportedFile(name string) bool {
+	return filepath.Ext(name) == ".tf" || filepath.Ext(name) == ".tofu" || isJSONFile(name) || name == ".terraform.lock.hcl" || isTerragruntFile(name) || isVersionManagerFile(name)
+}
+
+// isHCLFile returns true if a given filename is a supported file in the HCL
+// native syntax.
+func isHCLFile(name string) bool {
+	return !isJSONFile(name) && !isVersionManagerFile(name)
+}
+
+// isTerragruntFile returns true if a given filename is a Terragrunt
+// configuration file.
+func isTerragruntFile(filename string) bool {
+	return filepath.Base(filename) == "terragrunt.hcl"
 }
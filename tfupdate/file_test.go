@@ -2,9 +2,13 @@ package tfupdate
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/spf13/afero"
@@ -70,6 +74,53 @@ required_version = "0.12.6"
 terraform {
 required_version = "0.12.6"
 }
+`,
+			ok: true,
+		},
+		{
+			filename: "unformatted_no_format.tf",
+			src: `
+terraform {
+required_version   =   "0.12.6" # comment
+}
+`,
+			o: Option{
+				updateType: "terraform",
+				version:    "0.12.7",
+				noFormat:   true,
+			},
+			want: `
+terraform {
+required_version   =   "0.12.7" # comment
+}
+`,
+			ok: true,
+		},
+		{
+			filename: "valid.tf.json",
+			src: `{
+    "terraform": {"required_version": "0.12.6"}
+}
+`,
+			o: Option{
+				updateType: "terraform",
+				version:    "0.12.7",
+			},
+			want: `{
+    "terraform": {"required_version": "0.12.7"}
+}
+`,
+			ok: true,
+		},
+		{
+			filename: ".tool-versions",
+			src: `terraform 0.12.6
+`,
+			o: Option{
+				updateType: "terraform",
+				version:    "0.12.7",
+			},
+			want: `terraform 0.12.7
 `,
 			ok: true,
 		},
@@ -575,3 +626,466 @@ terraform {
 		})
 	}
 }
+
+func TestUpdateFileOrDirResults(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"a/terraform.tf": `
+terraform {
+  required_version = "0.12.6"
+}
+`,
+		"a/provider.tf": `
+provider "aws" {
+  version = "2.11.0"
+}
+`,
+		"a/b/terraform.tf": `
+terraform {
+required_version = "0.12.6"
+}
+`,
+		"a/c/terraform.tf": `
+terraform {
+  required_version = "0.12.7"
+}
+`,
+	}
+	for filename, src := range files {
+		if err := fs.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+			t.Fatalf("failed to create dir: %s", err)
+		}
+		if err := afero.WriteFile(fs, filename, []byte(src), 0644); err != nil {
+			t.Fatalf("failed to write file: %s", err)
+		}
+	}
+
+	o := Option{
+		updateType: "terraform",
+		version:    "0.12.7",
+		recursive:  true,
+	}
+	gc, err := NewGlobalContext(fs, o)
+	if err != nil {
+		t.Fatalf("failed to new global context: %s", err)
+	}
+
+	err = UpdateFileOrDir(context.Background(), gc, "a")
+	if err != nil {
+		t.Fatalf("UpdateFileOrDir() returns an unexpected error: %+v", err)
+	}
+
+	want := []Result{
+		{
+			Filename: "a/b/terraform.tf",
+			Original: []byte(files["a/b/terraform.tf"]),
+			Updated: []byte(`
+terraform {
+  required_version = "0.12.7"
+}
+`),
+			Changes: []Change{
+				{
+					Filename:    "a/b/terraform.tf",
+					BlockType:   "terraform",
+					BlockLabels: []string{},
+					Attribute:   "required_version",
+					OldValue:    "0.12.6",
+					NewValue:    "0.12.7",
+				},
+			},
+		},
+		{
+			Filename: "a/terraform.tf",
+			Original: []byte(files["a/terraform.tf"]),
+			Updated: []byte(`
+terraform {
+  required_version = "0.12.7"
+}
+`),
+			Changes: []Change{
+				{
+					Filename:    "a/terraform.tf",
+					BlockType:   "terraform",
+					BlockLabels: []string{},
+					Attribute:   "required_version",
+					OldValue:    "0.12.6",
+					NewValue:    "0.12.7",
+				},
+			},
+		},
+	}
+
+	got := gc.Results()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Results() returns %#v, but want = %#v", got, want)
+	}
+}
+
+func TestUpdateFileOrDirConcurrently(t *testing.T) {
+	src := `
+terraform {
+  required_version = "0.12.6"
+}
+`
+	// newFs returns a filesystem with many modules and given broken files.
+	newFs := func(broken ...string) afero.Fs {
+		fs := afero.NewMemMapFs()
+		files := map[string]string{
+			"a/terraform.tf":       src,
+			"a/.terraform-version": "0.12.6\n",
+		}
+		for i := range 20 {
+			dir := fmt.Sprintf("a/m%02d", i)
+			files[dir+"/.terraform-version"] = "0.12.6\n"
+			files[dir+"/terraform.tf"] = src
+		}
+		for _, filename := range broken {
+			files[filename] = "terraform {\n"
+		}
+		for filename, src := range files {
+			if err := fs.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+				t.Fatalf("failed to create dir: %s", err)
+			}
+			if err := afero.WriteFile(fs, filename, []byte(src), 0644); err != nil {
+				t.Fatalf("failed to write file: %s", err)
+			}
+		}
+		return fs
+	}
+
+	update := func(fs afero.Fs, jobs int, keepGoing bool) (*GlobalContext, error) {
+		o := Option{
+			updateType: "terraform",
+			version:    "~> 0.12.7",
+			recursive:  true,
+			jobs:       jobs,
+			keepGoing:  keepGoing,
+		}
+		gc, err := NewGlobalContext(fs, o)
+		if err != nil {
+			t.Fatalf("failed to new global context: %s", err)
+		}
+		return gc, UpdateFileOrDir(context.Background(), gc, "a")
+	}
+
+	t.Run("same as sequential", func(t *testing.T) {
+		// A file in the root module is walked after its subdirectories.
+		broken := []string{"a/m05/terraform.tf", "a/z.tf"}
+		want, err := update(newFs(broken...), 1, true)
+		if err != nil {
+			t.Fatalf("UpdateFileOrDir() with jobs = 1 returns an unexpected error: %s", err)
+		}
+		got, err := update(newFs(broken...), 4, true)
+		if err != nil {
+			t.Fatalf("UpdateFileOrDir() with jobs = 4 returns an unexpected error: %s", err)
+		}
+
+		if len(got.Results()) != 20 {
+			t.Errorf("Results() returns %d results, but want = %d", len(got.Results()), 20)
+		}
+		if !reflect.DeepEqual(got.Results(), want.Results()) {
+			t.Errorf("Results() returns %#v, but want = %#v", got.Results(), want.Results())
+		}
+		// Warnings are recorded in the order of the walk, where files and
+		// subdirectories are interleaved in alphabetical order.
+		if len(got.Warnings()) != 21 {
+			t.Errorf("Warnings() returns %d warnings, but want = %d", len(got.Warnings()), 21)
+		}
+		if !reflect.DeepEqual(got.Warnings(), want.Warnings()) {
+			t.Errorf("Warnings() returns %#v, but want = %#v", got.Warnings(), want.Warnings())
+		}
+		gotFailures := []string{}
+		for _, f := range got.Failures() {
+			gotFailures = append(gotFailures, f.Filename)
+		}
+		if !reflect.DeepEqual(gotFailures, broken) {
+			t.Errorf("Failures() returns %#v, but want = %#v", gotFailures, broken)
+		}
+	})
+
+	t.Run("report all errors", func(t *testing.T) {
+		broken := []string{"a/m03/terraform.tf", "a/m10/terraform.tf", "a/m17/terraform.tf"}
+		gc, err := update(newFs(broken...), 4, false)
+		if err == nil {
+			t.Fatalf("UpdateFileOrDir() expects to return an error, but no error")
+		}
+
+		// All errors are returned in the order of the walk.
+		got := err.Error()
+		last := -1
+		for _, filename := range broken {
+			i := strings.Index(got, filename)
+			if i <= last {
+				t.Errorf("UpdateFileOrDir() returns %s, but want errors for %#v in order", got, broken)
+				break
+			}
+			last = i
+		}
+
+		// An error in a module doesn't stop processing the others.
+		if len(gc.Results()) != 18 {
+			t.Errorf("Results() returns %d results, but want = %d", len(gc.Results()), 18)
+		}
+		for _, r := range gc.Results() {
+			if slices.Contains(broken, r.Filename) {
+				t.Errorf("%s is broken, but updated", r.Filename)
+			}
+		}
+	})
+}
+
+func TestUpdateFileOrDirKeepGoing(t *testing.T) {
+	src := `
+terraform {
+  required_version = "0.12.6"
+}
+`
+	want := `
+terraform {
+  required_version = "0.12.7"
+}
+`
+	files := map[string]string{
+		"a/a.tf":   "terraform {\n",
+		"a/b.tf":   src,
+		"a/c/c.tf": src,
+		"a/d/d.tf": "terraform {\n",
+		"a/e/e.tf": src,
+	}
+
+	cases := []struct {
+		desc string
+		jobs int
+	}{
+		{
+			desc: "sequential",
+			jobs: 1,
+		},
+		{
+			desc: "concurrent",
+			jobs: 4,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for filename, src := range files {
+				if err := fs.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := afero.WriteFile(fs, filename, []byte(src), 0644); err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			o := Option{
+				updateType: "terraform",
+				version:    "0.12.7",
+				recursive:  true,
+				jobs:       tc.jobs,
+				keepGoing:  true,
+			}
+			gc, err := NewGlobalContext(fs, o)
+			if err != nil {
+				t.Fatalf("failed to new global context: %s", err)
+			}
+
+			err = UpdateFileOrDir(context.Background(), gc, "a")
+			if err != nil {
+				t.Fatalf("UpdateFileOrDir() returns an unexpected error: %+v", err)
+			}
+
+			gotFailures := []string{}
+			for _, f := range gc.Failures() {
+				gotFailures = append(gotFailures, f.Filename)
+				if f.Err == nil {
+					t.Errorf("Failures() returns a failure without error: %#v", f)
+				}
+			}
+			wantFailures := []string{"a/a.tf", "a/d/d.tf"}
+			if !reflect.DeepEqual(gotFailures, wantFailures) {
+				t.Errorf("Failures() returns %#v, but want = %#v", gotFailures, wantFailures)
+			}
+
+			for _, filename := range []string{"a/b.tf", "a/c/c.tf", "a/e/e.tf"} {
+				got, err := afero.ReadFile(fs, filename)
+				if err != nil {
+					t.Fatalf("failed to read file: %s", err)
+				}
+				if string(got) != want {
+					t.Errorf("UpdateFileOrDir() updates %s to %s, but want = %s", filename, string(got), want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateFileChanges(t *testing.T) {
+	cases := []struct {
+		desc string
+		src  string
+		o    Option
+		want []Change
+	}{
+		{
+			desc: "provider object syntax",
+			src: `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "2.65.0"
+    }
+  }
+}
+`,
+			o: Option{
+				updateType: "provider",
+				name:       "aws",
+				version:    "2.66.0",
+			},
+			want: []Change{
+				{
+					Filename:    "main.tf",
+					BlockType:   "required_providers",
+					BlockLabels: []string{},
+					Attribute:   "aws.version",
+					OldValue:    "2.65.0",
+					NewValue:    "2.66.0",
+				},
+			},
+		},
+		{
+			desc: "provider string syntax and provider block",
+			src: `
+terraform {
+  required_providers {
+    null = "2.1.1"
+  }
+}
+
+provider "null" {
+  version = "2.1.1"
+}
+`,
+			o: Option{
+				updateType: "provider",
+				name:       "null",
+				version:    "2.1.2",
+			},
+			want: []Change{
+				{
+					Filename:    "main.tf",
+					BlockType:   "required_providers",
+					BlockLabels: []string{},
+					Attribute:   "null",
+					OldValue:    "2.1.1",
+					NewValue:    "2.1.2",
+				},
+				{
+					Filename:    "main.tf",
+					BlockType:   "provider",
+					BlockLabels: []string{"null"},
+					Attribute:   "version",
+					OldValue:    "2.1.1",
+					NewValue:    "2.1.2",
+				},
+			},
+		},
+		{
+			desc: "module",
+			src: `
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "2.14.0"
+}
+
+module "vpc_git" {
+  source = "git::https://example.com/vpc.git?ref=v2.14.0"
+}
+`,
+			o: Option{
+				updateType: "module",
+				name:       "terraform-aws-modules/vpc/aws",
+				version:    "2.15.0",
+			},
+			want: []Change{
+				{
+					Filename:    "main.tf",
+					BlockType:   "module",
+					BlockLabels: []string{"vpc"},
+					Attribute:   "version",
+					OldValue:    "2.14.0",
+					NewValue:    "2.15.0",
+				},
+			},
+		},
+		{
+			desc: "module source",
+			src: `
+module "vpc" {
+  source = "git::https://example.com/vpc.git?ref=v2.14.0"
+}
+`,
+			o: Option{
+				updateType: "module",
+				name:       "git::https://example.com/vpc.git",
+				version:    "2.15.0",
+			},
+			want: []Change{
+				{
+					Filename:    "main.tf",
+					BlockType:   "module",
+					BlockLabels: []string{"vpc"},
+					Attribute:   "source",
+					OldValue:    "git::https://example.com/vpc.git?ref=v2.14.0",
+					NewValue:    "git::https://example.com/vpc.git?ref=v2.15.0",
+				},
+			},
+		},
+		{
+			desc: "no change",
+			src: `
+terraform {
+  required_version = "0.12.7"
+}
+`,
+			o: Option{
+				updateType: "terraform",
+				version:    "0.12.7",
+			},
+			want: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			err := afero.WriteFile(fs, "main.tf", []byte(tc.src), 0644)
+			if err != nil {
+				t.Fatalf("failed to write file: %s", err)
+			}
+
+			gc, err := NewGlobalContext(fs, tc.o)
+			if err != nil {
+				t.Fatalf("failed to new global context: %s", err)
+			}
+
+			err = UpdateFileOrDir(context.Background(), gc, "main.tf")
+			if err != nil {
+				t.Fatalf("UpdateFileOrDir() returns an unexpected error: %+v", err)
+			}
+
+			var got []Change
+			for _, r := range gc.Results() {
+				got = append(got, r.Changes...)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("UpdateFileOrDir() with o = %#v records changes %#v, but want = %#v", tc.o, got, tc.want)
+			}
+		})
+	}
+}
@@ -103,6 +103,92 @@ func getAttributeValueAsUnquotedString(attr *hclwrite.Attribute) string {
 	return value
 }
 
+// getAttributeValueAsStringList returns a list of string literals in a value
+// of Attribute such as hashes in the dependency lock file.
+// Elements other than string literals are ignored.
+func getAttributeValueAsStringList(attr *hclwrite.Attribute) []string {
+	list := []string{}
+	for _, t := range attr.Expr().BuildTokens(nil) {
+		if t.Type == hclsyntax.TokenQuotedLit {
+			list = append(list, string(t.Bytes))
+		}
+	}
+	return list
+}
+
+// setAttributeValueAsString sets a string value to the attribute.
+// If the current expression is a quoted string literal, it rewrites only the
+// bytes of the literal token in place, so that the original formatting such
+// as spaces and comments around it is preserved. Otherwise, it falls back to
+// (*hclwrite.Body).SetAttributeValue(), which replaces the whole expression
+// and creates a new attribute if not found.
+func setAttributeValueAsString(body *hclwrite.Body, name string, value string) {
+	newTokens := hclwrite.TokensForValue(cty.StringVal(value))
+
+	// A single space before the value is the canonical style.
+	spacesBefore := 1
+	if attr := body.GetAttribute(name); attr != nil {
+		tokens := attr.Expr().BuildTokens(nil)
+		if len(tokens) == 3 && len(newTokens) == 3 &&
+			tokens[0].Type == hclsyntax.TokenOQuote &&
+			tokens[1].Type == hclsyntax.TokenQuotedLit &&
+			tokens[2].Type == hclsyntax.TokenCQuote {
+			// The tokens returned by BuildTokens point to the original ones.
+			// Reuse the escaped bytes generated by TokensForValue.
+			tokens[1].Bytes = newTokens[1].Bytes
+			return
+		}
+		if len(tokens) != 0 {
+			spacesBefore = tokens[0].SpacesBefore
+		}
+	}
+
+	// The SetAttributeValue doesn't preserve an original SpaceBefore value of
+	// the expression, so we restore it by ourselves.
+	// Note that we don't use the return value of SetAttributeValue because it
+	// returns nil when a new attribute is created.
+	body.SetAttributeValue(name, cty.StringVal(value))
+	for _, t := range body.GetAttribute(name).BuildTokens(nil) {
+		switch t.Type {
+		case hclsyntax.TokenEqual:
+			t.SpacesBefore = 1
+		case hclsyntax.TokenOQuote:
+			t.SpacesBefore = spacesBefore
+			return
+		}
+	}
+}
+
+// compactBlankLines removes consecutive blank lines and trailing blank lines
+// in a given body, which are left by (*hclwrite.Body).RemoveBlock().
+// There is no way to remove unstructured tokens such as newlines with the
+// hclwrite API, so we rebuild the body from the tokens. Note that the body
+// no longer has structured items such as blocks after this operation.
+func compactBlankLines(body *hclwrite.Body) {
+	compacted := hclwrite.Tokens{}
+	newlines := 0
+	for _, t := range body.BuildTokens(nil) {
+		if t.Type == hclsyntax.TokenNewline {
+			newlines++
+			if newlines > 2 {
+				continue
+			}
+		} else {
+			newlines = 0
+		}
+		compacted = append(compacted, t)
+	}
+
+	for len(compacted) >= 2 &&
+		compacted[len(compacted)-1].Type == hclsyntax.TokenNewline &&
+		compacted[len(compacted)-2].Type == hclsyntax.TokenNewline {
+		compacted = compacted[:len(compacted)-1]
+	}
+
+	body.Clear()
+	body.AppendUnstructuredTokens(compacted)
+}
+
 // tokensForListPerLine builds a hclwrite.Tokens for a given list, but breaks the line for each element.
 func tokensForListPerLine(list []string) hclwrite.Tokens {
 	// The original TokensForValue implementation does not break line by line for list,
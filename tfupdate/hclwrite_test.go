@@ -283,6 +283,95 @@ foo = "123"
 	}
 }
 
+func TestSetAttributeValueAsString(t *testing.T) {
+	cases := []struct {
+		desc  string
+		src   string
+		name  string
+		value string
+		want  string
+	}{
+		{
+			desc: "preserve formatting",
+			src: `
+foo   =   "123" # comment
+bar="456"
+`,
+			name:  "foo",
+			value: "124",
+			want: `
+foo   =   "124" # comment
+bar="456"
+`,
+		},
+		{
+			desc: "escape",
+			src: `
+foo = "123"
+`,
+			name:  "foo",
+			value: `a"b`,
+			want: `
+foo = "a\"b"
+`,
+		},
+		{
+			desc: "template",
+			src: `
+foo = "${var.foo}"
+`,
+			name:  "foo",
+			value: "124",
+			want: `
+foo = "124"
+`,
+		},
+		{
+			desc: "empty string",
+			src: `
+foo = ""
+`,
+			name:  "foo",
+			value: "124",
+			want: `
+foo = "124"
+`,
+		},
+		{
+			desc: "not found",
+			src: `
+bar = "456"
+`,
+			name:  "foo",
+			value: "124",
+			want: `
+bar = "456"
+foo = "124"
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			f, diags := hclwrite.ParseConfig([]byte(tc.src), "", hcl.Pos{Line: 1, Column: 1})
+			if len(diags) != 0 {
+				for _, diag := range diags {
+					t.Logf("- %s", diag.Error())
+				}
+				t.Fatalf("unexpected diagnostics")
+			}
+
+			setAttributeValueAsString(f.Body(), tc.name, tc.value)
+
+			got := string(f.BuildTokens(nil).Bytes())
+
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("got: %s, want = %s, diff = %s", got, tc.want, diff)
+			}
+		})
+	}
+}
+
 func TestTokensForListPerLine(t *testing.T) {
 	cases := []struct {
 		desc string
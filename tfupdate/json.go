@@ -0,0 +1,251 @@
+package tfupdate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	hcljson "github.com/hashicorp/hcl/v2/json"
+)
+
+// isJSONFile returns true if a given filename is a configuration file in the
+// JSON syntax.
+func isJSONFile(filename string) bool {
+	return strings.HasSuffix(filename, ".tf.json") || strings.HasSuffix(filename, ".tofu.json")
+}
+
+// jsonUpdater is an optional interface for updaters which support the JSON
+// configuration syntax.
+type jsonUpdater interface {
+	// updateJSON updates a version constraint in the JSON configuration.
+	// Note that this method will record edits to the file passed as an argument.
+	updateJSON(ctx context.Context, mc *ModuleContext, filename string, f *jsonFile) error
+}
+
+// UpdateJSON reads a configuration in the JSON syntax from io.Reader, updates
+// version constraints and writes updated contents to io.Writer.
+// Only the changed values are rewritten, so the original key order and
+// formatting are preserved.
+// If contents changed successfully, it returns true, or otherwise returns false.
+// If an error occurs, nothing is written to the output stream.
+func UpdateJSON(ctx context.Context, mc *ModuleContext, r io.Reader, w io.Writer, filename string) (bool, error) {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return false, fmt.Errorf("failed to read input: %s", err)
+	}
+
+	f, err := parseJSONConfig(input, filename)
+	if err != nil {
+		return false, err
+	}
+
+	u, ok := mc.Updater().(jsonUpdater)
+	if ok {
+		if err := u.updateJSON(ctx, mc, filename, f); err != nil {
+			return false, err
+		}
+	}
+
+	output := f.Bytes()
+
+	if _, err := w.Write(output); err != nil {
+		return false, fmt.Errorf("failed to write output: %s", err)
+	}
+
+	isUpdated := !bytes.Equal(input, output)
+	return isUpdated, nil
+}
+
+// jsonString is a string value in the source.
+type jsonString struct {
+	// start and end are the byte offsets of the quoted value in the source.
+	start int
+	end   int
+
+	// str is a decoded value.
+	str string
+}
+
+// jsonEdit is a replacement of a byte range in the source.
+type jsonEdit struct {
+	start int
+	end   int
+	text  []byte
+}
+
+// jsonFile is a parsed configuration file in the JSON syntax.
+// We parse it with the HCL JSON parser to find blocks and attributes, and
+// only replace byte ranges of changed string values, because rewriting the
+// whole document loses the original key order and formatting.
+type jsonFile struct {
+	src   []byte
+	body  hcl.Body
+	edits []jsonEdit
+}
+
+// parseJSONConfig parses a configuration file in the JSON syntax.
+func parseJSONConfig(src []byte, filename string) (*jsonFile, error) {
+	file, diags := hcljson.Parse(src, filename)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse input: %s", diags)
+	}
+
+	return &jsonFile{src: src, body: file.Body}, nil
+}
+
+// Bytes returns the source with all edits applied.
+func (f *jsonFile) Bytes() []byte {
+	edits := slices.Clone(f.edits)
+	slices.SortFunc(edits, func(a, b jsonEdit) int {
+		return a.start - b.start
+	})
+
+	var buf bytes.Buffer
+	pos := 0
+	for _, e := range edits {
+		buf.Write(f.src[pos:e.start])
+		buf.Write(e.text)
+		pos = e.end
+	}
+	buf.Write(f.src[pos:])
+	return buf.Bytes()
+}
+
+// setString replaces a string value with a given string.
+func (f *jsonFile) setString(v *jsonString, s string) {
+	if v.str == s {
+		return
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// Keep characters such as < and > as is in version constraints.
+	enc.SetEscapeHTML(false)
+	// Encoding a string never fails.
+	_ = enc.Encode(s)
+
+	// Remove an edit for the same value if any.
+	f.edits = slices.DeleteFunc(f.edits, func(e jsonEdit) bool {
+		return e.start == v.start
+	})
+	f.edits = append(f.edits, jsonEdit{
+		start: v.start,
+		end:   v.end,
+		text:  bytes.TrimSuffix(buf.Bytes(), []byte("\n")),
+	})
+	v.str = s
+}
+
+// stringValue returns a string value of a given expression.
+// If the expression is not a string literal, it returns nil.
+// Note that we decode the source as is instead of evaluating the expression,
+// because a string in the JSON syntax is evaluated as a template.
+func (f *jsonFile) stringValue(expr hcl.Expression) *jsonString {
+	r := expr.Range()
+	var s string
+	if err := json.Unmarshal(f.src[r.Start.Byte:r.End.Byte], &s); err != nil {
+		return nil
+	}
+	return &jsonString{start: r.Start.Byte, end: r.End.Byte, str: s}
+}
+
+// getString returns a string value for a given attribute of the body.
+// If not found or the value is not a string, it returns nil.
+func (f *jsonFile) getString(body hcl.Body, name string) *jsonString {
+	attr := getJSONAttribute(body, name)
+	if attr == nil {
+		return nil
+	}
+	return f.stringValue(attr.Expr)
+}
+
+// getJSONAttribute returns an attribute for a given name of the body.
+// If not found, it returns nil.
+func getJSONAttribute(body hcl.Body, name string) *hcl.Attribute {
+	content, _, _ := body.PartialContent(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: name}},
+	})
+	if content == nil {
+		return nil
+	}
+	return content.Attributes[name]
+}
+
+// getMemberString returns a string value for a given key of the object
+// expression such as { "source": "hashicorp/aws", "version": "2.65.0" }.
+// If not found or the value is not a string, it returns nil.
+func (f *jsonFile) getMemberString(expr hcl.Expression, key string) *jsonString {
+	pairs, diags := hcl.ExprMap(expr)
+	if diags.HasErrors() {
+		return nil
+	}
+	for _, kv := range pairs {
+		if k := f.stringValue(kv.Key); k != nil && k.str == key {
+			return f.stringValue(kv.Value)
+		}
+	}
+	return nil
+}
+
+// isJSONObject returns true if a given expression is an object.
+func isJSONObject(expr hcl.Expression) bool {
+	_, diags := hcl.ExprMap(expr)
+	return !diags.HasErrors()
+}
+
+// allJSONAttributes returns all attributes of the body in the source order.
+func allJSONAttributes(body hcl.Body) []*hcl.Attribute {
+	attrs, _ := body.JustAttributes()
+	sorted := make([]*hcl.Attribute, 0, len(attrs))
+	for _, attr := range attrs {
+		sorted = append(sorted, attr)
+	}
+	slices.SortFunc(sorted, func(a, b *hcl.Attribute) int {
+		return a.Range.Start.Byte - b.Range.Start.Byte
+	})
+	return sorted
+}
+
+// allMatchingJSONBlocks returns bodies of blocks without labels for a given
+// type such as terraform.
+func allMatchingJSONBlocks(body hcl.Body, typeName string) []hcl.Body {
+	bodies := []hcl.Body{}
+	for _, b := range matchingJSONBlocks(body, typeName, nil) {
+		bodies = append(bodies, b.Body)
+	}
+	return bodies
+}
+
+// jsonLabeledBlock is a block body with a label.
+type jsonLabeledBlock struct {
+	label string
+	body  hcl.Body
+}
+
+// allMatchingJSONLabeledBlocks returns bodies of blocks with a single label
+// for a given type such as provider or module.
+func allMatchingJSONLabeledBlocks(body hcl.Body, typeName string) []jsonLabeledBlock {
+	blocks := []jsonLabeledBlock{}
+	for _, b := range matchingJSONBlocks(body, typeName, []string{"name"}) {
+		blocks = append(blocks, jsonLabeledBlock{label: b.Labels[0], body: b.Body})
+	}
+	return blocks
+}
+
+// matchingJSONBlocks returns blocks for a given type in the source order.
+// Blocks which cannot be decoded are ignored, because we only update what we
+// can find.
+func matchingJSONBlocks(body hcl.Body, typeName string, labelNames []string) hcl.Blocks {
+	content, _, _ := body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: typeName, LabelNames: labelNames}},
+	})
+	if content == nil {
+		return nil
+	}
+	return content.Blocks
+}
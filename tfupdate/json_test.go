@@ -0,0 +1,295 @@
+package tfupdate
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestUpdateJSON(t *testing.T) {
+	cases := []struct {
+		filename  string
+		src       string
+		o         Option
+		want      string
+		isUpdated bool
+		ok        bool
+	}{
+		{
+			filename: "main.tf.json",
+			src: `{
+  "terraform": {
+    "required_version": "0.12.6"
+  }
+}
+`,
+			o: Option{
+				updateType: "terraform",
+				version:    "0.12.7",
+			},
+			want: `{
+  "terraform": {
+    "required_version": "0.12.7"
+  }
+}
+`,
+			isUpdated: true,
+			ok:        true,
+		},
+		{
+			filename: "main.tofu.json",
+			src:      `{"terraform":[{"required_version":"1.8.0"}],"locals":{"foo":1}}`,
+			o: Option{
+				updateType: "opentofu",
+				version:    "1.9.0",
+			},
+			want:      `{"terraform":[{"required_version":"1.9.0"}],"locals":{"foo":1}}`,
+			isUpdated: true,
+			ok:        true,
+		},
+		{
+			filename: "main.tf.json",
+			src: `{
+  "terraform": {
+    "required_providers": {
+      "null": "2.1.1",
+      "aws": {
+        "version": "2.65.0",
+        "source": "hashicorp/aws"
+      }
+    }
+  },
+  "provider": {
+    "aws": {
+      "region": "ap-northeast-1",
+      "version": "2.65.0"
+    }
+  }
+}
+`,
+			o: Option{
+				updateType: "provider",
+				name:       "aws",
+				version:    ">= 2.70.0, < 3.0",
+			},
+			want: `{
+  "terraform": {
+    "required_providers": {
+      "null": "2.1.1",
+      "aws": {
+        "version": ">= 2.70.0, < 3.0",
+        "source": "hashicorp/aws"
+      }
+    }
+  },
+  "provider": {
+    "aws": {
+      "region": "ap-northeast-1",
+      "version": ">= 2.70.0, < 3.0"
+    }
+  }
+}
+`,
+			isUpdated: true,
+			ok:        true,
+		},
+		{
+			filename: "main.tf.json",
+			src: `{
+  "terraform": {
+    "required_providers": {
+      "null": "2.1.1"
+    }
+  }
+}
+`,
+			o: Option{
+				updateType: "provider",
+				name:       "null",
+				version:    "2.1.2",
+			},
+			want: `{
+  "terraform": {
+    "required_providers": {
+      "null": "2.1.2"
+    }
+  }
+}
+`,
+			isUpdated: true,
+			ok:        true,
+		},
+		{
+			filename: "main.tf.json",
+			src: `{
+  "module": {
+    "vpc": {
+      "source": "terraform-aws-modules/vpc/aws",
+      "version": "2.5.0"
+    },
+    "consul": [
+      {
+        "source": "git::https://example.com/consul.git?ref=v1.2.0"
+      }
+    ]
+  }
+}
+`,
+			o: Option{
+				updateType: "module",
+				name:       "terraform-aws-modules/vpc/aws",
+				version:    "2.6.0",
+			},
+			want: `{
+  "module": {
+    "vpc": {
+      "source": "terraform-aws-modules/vpc/aws",
+      "version": "2.6.0"
+    },
+    "consul": [
+      {
+        "source": "git::https://example.com/consul.git?ref=v1.2.0"
+      }
+    ]
+  }
+}
+`,
+			isUpdated: true,
+			ok:        true,
+		},
+		{
+			filename: "main.tf.json",
+			src: `{
+  "module": {
+    "consul": [
+      {
+        "source": "git::https://example.com/consul.git?ref=v1.2.0"
+      }
+    ]
+  }
+}
+`,
+			o: Option{
+				updateType: "module",
+				name:       "git::https://example.com/consul.git",
+				version:    "1.3.0",
+			},
+			want: `{
+  "module": {
+    "consul": [
+      {
+        "source": "git::https://example.com/consul.git?ref=v1.3.0"
+      }
+    ]
+  }
+}
+`,
+			isUpdated: true,
+			ok:        true,
+		},
+		{
+			filename: "main.tf.json",
+			src: `{
+  "terraform": {
+    "required_version": "0.12.6"
+  }
+}
+`,
+			o: Option{
+				updateType: "provider",
+				name:       "aws",
+				version:    "2.23.0",
+			},
+			want: `{
+  "terraform": {
+    "required_version": "0.12.6"
+  }
+}
+`,
+			isUpdated: false,
+			ok:        true,
+		},
+		{
+			filename: "main.tf.json",
+			src: `{
+  "terraform": {
+`,
+			o: Option{
+				updateType: "terraform",
+				version:    "0.12.7",
+			},
+			want:      "",
+			isUpdated: false,
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		gc, err := NewGlobalContext(afero.NewMemMapFs(), tc.o)
+		if err != nil {
+			t.Fatalf("failed to new global context: %s", err)
+		}
+
+		mc, err := NewModuleContext(".", gc)
+		if err != nil {
+			t.Fatalf("failed to new module context: %s", err)
+		}
+
+		r := bytes.NewBufferString(tc.src)
+		w := &bytes.Buffer{}
+		isUpdated, err := UpdateJSON(context.Background(), mc, r, w, tc.filename)
+		if tc.ok && err != nil {
+			t.Errorf("UpdateJSON() with src = %s, o = %#v returns unexpected err: %+v", tc.src, tc.o, err)
+		}
+
+		if !tc.ok && err == nil {
+			t.Errorf("UpdateJSON() with src = %s, o = %#v expects to return an error, but no error", tc.src, tc.o)
+		}
+
+		if isUpdated != tc.isUpdated {
+			t.Errorf("UpdateJSON() with src = %s, o = %#v returns unexpected isUpdated, got = %t, want = %t", tc.src, tc.o, isUpdated, tc.isUpdated)
+		}
+
+		if got := w.String(); got != tc.want {
+			t.Errorf("UpdateJSON() with src = %s, o = %#v returns %s, but want = %s", tc.src, tc.o, got, tc.want)
+		}
+	}
+}
+
+func TestJSONFileSetString(t *testing.T) {
+	cases := []struct {
+		src   string
+		value string
+		want  string
+	}{
+		{
+			src:   `{"a": "1.0", "b": "c"}`,
+			value: ">= 1.1, < 2.0",
+			want:  `{"a": ">= 1.1, < 2.0", "b": "c"}`,
+		},
+		{
+			src:   `{"a": "1.0", "b": "c"}`,
+			value: "1.0",
+			want:  `{"a": "1.0", "b": "c"}`,
+		},
+		{
+			src:   `{"a": "esc\"aped", "b": "c"}`,
+			value: `new"value`,
+			want:  `{"a": "new\"value", "b": "c"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		f, err := parseJSONConfig([]byte(tc.src), "main.tf.json")
+		if err != nil {
+			t.Fatalf("failed to parse json: %s", err)
+		}
+
+		f.setString(f.getString(f.body, "a"), tc.value)
+		if got := string(f.Bytes()); got != tc.want {
+			t.Errorf("setString() with src = %s, value = %s returns %s, but want = %s", tc.src, tc.value, got, tc.want)
+		}
+	}
+}
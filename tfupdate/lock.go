@@ -4,17 +4,55 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"maps"
 	"net/url"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 
+	version "github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	tfaddr "github.com/hashicorp/terraform-registry-address"
 	svchost "github.com/hashicorp/terraform-svchost"
+	"github.com/minamijoyo/terraform-config-inspect/tfconfig"
 	"github.com/minamijoyo/tfupdate/lock"
 	"github.com/minamijoyo/tfupdate/tfregistry"
-	"github.com/zclconf/go-cty/cty"
+	"github.com/spf13/afero"
 )
 
+// LockPolicy is a set of parameters to update dependency lock files.
+type LockPolicy struct {
+	// Prune is a flag to remove provider blocks which are no longer required
+	// by the module and its local child modules.
+	Prune bool
+
+	// Create is a flag to create a new lock file in each module which has
+	// pinned providers but no lock file yet.
+	Create bool
+
+	// CacheDir is a directory to persist hash values of provider packages
+	// across processes. If empty, they are cached only in memory.
+	CacheDir string
+
+	// PluginDirs is a list of local directories which contain unpacked provider
+	// packages, such as TF_PLUGIN_CACHE_DIR. They are looked up before
+	// downloading provider packages.
+	PluginDirs []string
+
+	// Parallelism is the maximum number of provider packages downloaded
+	// concurrently. If less than 1, the default value is used.
+	Parallelism int
+}
+
+// lockFileHeader is a comment at the beginning of a new lock file, which is
+// the same as terraform generates.
+const lockFileHeader = `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+`
+
 // LockUpdater is a updater implementation which updates the dependency lock file.
 type LockUpdater struct {
 	platforms []string
@@ -22,22 +60,44 @@ type LockUpdater struct {
 	// index is a cached index for updating dependency lock files.
 	index lock.Index
 
+	// registry is a client for listing available versions of providers.
+	registry tfregistry.ProviderV1API
+
+	// mu protects availableVersions because modules may be processed
+	// concurrently.
+	mu sync.Mutex
+
+	// availableVersions is a cache of available versions of providers sorted in
+	// descending order. The key is a provider address such as hashicorp/null.
+	availableVersions map[string]version.Collection
+
 	// tfregistryConfig is a configuration for Terraform Registry API.
 	tfregistryConfig tfregistry.Config
 }
 
 // NewLockUpdater is a factory method which returns a LockUpdater instance.
-func NewLockUpdater(platforms []string, tfregistryConfig tfregistry.Config) (Updater, error) {
+// If cacheDir is not empty, hash values of provider packages are persisted in
+// the directory. The pluginDirs is a list of local directories to look up
+// unpacked provider packages before downloading them. The parallelism is the
+// maximum number of provider packages downloaded concurrently.
+func NewLockUpdater(platforms []string, tfregistryConfig tfregistry.Config, cacheDir string, pluginDirs []string, parallelism int) (Updater, error) {
 	// Create a new index with the provided registry config
-	index, err := lock.NewIndexFromConfig(tfregistryConfig)
+	index, err := lock.NewIndexFromConfig(tfregistryConfig, cacheDir, pluginDirs, parallelism)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := tfregistry.NewClient(tfregistryConfig)
 	if err != nil {
 		return nil, err
 	}
 
 	return &LockUpdater{
-		platforms:        platforms,
-		index:            index,
-		tfregistryConfig: tfregistryConfig,
+		platforms:         platforms,
+		index:             index,
+		registry:          registry,
+		availableVersions: make(map[string]version.Collection),
+		tfregistryConfig:  tfregistryConfig,
 	}, nil
 }
 
@@ -49,12 +109,20 @@ func (u *LockUpdater) Update(ctx context.Context, mc *ModuleContext, filename st
 		return nil
 	}
 
-	return u.updateLockfile(ctx, mc, f)
+	return u.updateLockfile(ctx, mc, filename, f)
 }
 
 // updateLockfile updates the dependency lock file.
-func (u *LockUpdater) updateLockfile(ctx context.Context, mc *ModuleContext, f *hclwrite.File) error {
-	for _, p := range mc.SelectedProviders() {
+func (u *LockUpdater) updateLockfile(ctx context.Context, mc *ModuleContext, filename string, f *hclwrite.File) error {
+	// Like terraform, constraints in the lock file are merged from all modules
+	// which require the provider. We only know the current module and its local
+	// child modules, so constraints in remote modules are not included.
+	reqs, reqsErr := u.collectProviderRequirements(mc)
+	if reqsErr != nil {
+		log.Printf("[DEBUG] LockUpdater.updateLockfile: ignore constraints in child modules: %s", reqsErr)
+	}
+
+	for _, p := range u.selectProviders(mc, reqs) {
 		pAddr, err := u.fullyQualifiedProviderAddress(p.Source)
 		if err != nil {
 			// Unsupported formats, such as legacy abbreviated notation, will result
@@ -65,9 +133,18 @@ func (u *LockUpdater) updateLockfile(ctx context.Context, mc *ModuleContext, f *
 		}
 
 		pBlock := f.Body().FirstMatchingBlock("provider", []string{pAddr})
+		if p.Version == "" {
+			// The version constraints are not pinned to a specific version.
+			v, err := u.resolveProviderVersion(ctx, p)
+			if err != nil {
+				return err
+			}
+			p.Version = v
+		}
+
 		if pBlock != nil {
 			// update the existing provider block
-			err := u.updateProviderBlock(ctx, pBlock, p)
+			err := u.updateProviderBlock(ctx, mc, filename, pBlock, p)
 			if err != nil {
 				return err
 			}
@@ -76,42 +153,249 @@ func (u *LockUpdater) updateLockfile(ctx context.Context, mc *ModuleContext, f *
 			f.Body().AppendNewline()
 			pBlock = f.Body().AppendNewBlock("provider", []string{pAddr})
 
-			err := u.updateProviderBlock(ctx, pBlock, p)
+			err := u.updateProviderBlock(ctx, mc, filename, pBlock, p)
 			if err != nil {
 				return err
 			}
 		}
 	}
 
+	if mc.Option().prune {
+		if reqsErr != nil {
+			mc.gc.addWarning(fmt.Sprintf("%s: skip pruning provider blocks: %s", filename, reqsErr))
+		} else {
+			u.pruneProviderBlocks(mc, filename, f, reqs)
+		}
+	}
+
 	return nil
 }
 
+// pruneProviderBlocks removes provider blocks which are no longer required by
+// the module from the dependency lock file. Note that changes of constraints
+// and hashes are not recorded because they are removed with the version.
+// This must be the last step of updating the lock file because the body is
+// rebuilt from tokens to remove blank lines left by removed blocks.
+// The reqs is a set of providers required by the module and its local child
+// modules, which is collected when updating the lock file.
+func (u *LockUpdater) pruneProviderBlocks(mc *ModuleContext, filename string, f *hclwrite.File, reqs *providerRequirements) {
+	if len(reqs.remoteModules) > 0 {
+		// Providers required by remote modules cannot be known without
+		// downloading them.
+		mc.gc.addWarning(fmt.Sprintf("%s: skip pruning provider blocks: remote module is not supported: %s", filename, reqs.remoteModules[0]))
+		return
+	}
+
+	pruned := false
+	for _, pBlock := range allMatchingBlocksByType(f.Body(), "provider") {
+		if len(pBlock.Labels()) == 0 {
+			continue
+		}
+		if _, ok := reqs.constraints[pBlock.Labels()[0]]; ok {
+			continue
+		}
+
+		vVal := ""
+		if vAttr := pBlock.Body().GetAttribute("version"); vAttr != nil {
+			vVal = getAttributeValueAsUnquotedString(vAttr)
+		}
+
+		log.Printf("[DEBUG] prune provider block in lock file: address = %s", pBlock.Labels()[0])
+		f.Body().RemoveBlock(pBlock)
+		mc.recordChange(newChange(filename, "provider", pBlock.Labels(), "version", vVal, ""))
+		pruned = true
+	}
+
+	if pruned {
+		compactBlankLines(f.Body())
+	}
+}
+
+// providerRequirements is a set of version constraints of providers required
+// by a module and its local child modules.
+type providerRequirements struct {
+	// constraints is a map from a fully qualified provider address to a list of
+	// version constraints declared in each module.
+	constraints map[string][]string
+
+	// sources is a map from a fully qualified provider address to a source
+	// address first declared in any of the modules.
+	sources map[string]string
+
+	// remoteModules is a list of sources of remote modules called by any of the
+	// modules. Providers required by them cannot be known without downloading
+	// them.
+	remoteModules []string
+}
+
+// collectProviderRequirements walks the module and its local child modules,
+// and collects version constraints of providers required by them.
+// Remote modules are not walked, but recorded in the result.
+func (u *LockUpdater) collectProviderRequirements(mc *ModuleContext) (*providerRequirements, error) {
+	if mc.moduleCalls == nil {
+		return nil, fmt.Errorf("failed to load module: %s", mc.dir)
+	}
+
+	reqs := &providerRequirements{
+		constraints: make(map[string][]string),
+		sources:     make(map[string]string),
+	}
+	visited := make(map[string]bool)
+
+	var walk func(dir string, requiredProviders map[string]*tfconfig.ProviderRequirement, moduleCalls map[string]*tfconfig.ModuleCall) error
+	walk = func(dir string, requiredProviders map[string]*tfconfig.ProviderRequirement, moduleCalls map[string]*tfconfig.ModuleCall) error {
+		visited[dir] = true
+
+		for name, p := range requiredProviders {
+			source := p.Source
+			if source == "" {
+				// An implicit provider belongs to the official hashicorp namespace.
+				source = "hashicorp/" + name
+			}
+			pAddr, err := u.fullyQualifiedProviderAddress(source)
+			if err != nil {
+				log.Printf("[DEBUG] LockUpdater.collectProviderRequirements: ignore legacy provider address notation: %s", source)
+				continue
+			}
+			reqs.constraints[pAddr] = append(reqs.constraints[pAddr], p.VersionConstraints...)
+			if _, ok := reqs.sources[pAddr]; !ok {
+				reqs.sources[pAddr] = source
+			}
+		}
+
+		// Sort to get stable results
+		for _, name := range slices.Sorted(maps.Keys(moduleCalls)) {
+			c := moduleCalls[name]
+			if !isLocalModuleSource(c.Source) {
+				reqs.remoteModules = append(reqs.remoteModules, c.Source)
+				continue
+			}
+
+			child := filepath.Join(dir, c.Source)
+			if visited[child] {
+				continue
+			}
+
+			m, diags := tfconfig.LoadModuleFromFilesystem(aferoToTfconfigFS(mc.FS()), child)
+			if diags.HasErrors() {
+				return fmt.Errorf("failed to load module: %s", child)
+			}
+
+			if err := walk(child, m.RequiredProviders, m.ModuleCalls); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(mc.dir, mc.requiredProviders, mc.moduleCalls); err != nil {
+		return nil, err
+	}
+
+	return reqs, nil
+}
+
+// isLocalModuleSource returns true if a given module source is a local path.
+func isLocalModuleSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+// collectChildModuleDirs returns a set of directories which are called as a
+// local module by any of modules in a given file or directory. Terraform runs
+// only in root modules, so they are not expected to have a lock file.
+// Note that a module called only from outside of the path is treated as a
+// root module.
+func collectChildModuleDirs(gc *GlobalContext, path string) (map[string]bool, error) {
+	dirs := make(map[string]bool)
+	err := walkFileOrDir(gc, path, func(mc *ModuleContext) error {
+		for _, c := range mc.moduleCalls {
+			if isLocalModuleSource(c.Source) {
+				dirs[filepath.Join(mc.dir, c.Source)] = true
+			}
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return dirs, nil
+}
+
+// selectProviders returns providers to be recorded in the dependency lock file
+// sorted by the fully qualified address. Like terraform, it includes providers
+// required only by local child modules, and their constraints are merged from
+// all modules. If the reqs is nil, it returns providers selected in the
+// current module only.
+func (u *LockUpdater) selectProviders(mc *ModuleContext, reqs *providerRequirements) []SelectedProvider {
+	selected := make(map[string]SelectedProvider)
+	for _, p := range mc.SelectedProviders() {
+		pAddr, err := u.fullyQualifiedProviderAddress(p.Source)
+		if err != nil {
+			log.Printf("[DEBUG] LockUpdater.selectProviders: ignore legacy provider address notation: %s", p.Source)
+			continue
+		}
+		selected[pAddr] = p
+	}
+
+	if reqs != nil {
+		for pAddr, constraints := range reqs.constraints {
+			c := normalizeVersionConstraints(constraints)
+			if c == "" {
+				// Ignore if no version is specified in any of the modules.
+				continue
+			}
+
+			p, ok := selected[pAddr]
+			if !ok {
+				p = SelectedProvider{Source: reqs.sources[pAddr]}
+			}
+			if p.Version == "" {
+				p.Version = selectVersion(constraints)
+			}
+			p.Constraints = c
+			selected[pAddr] = p
+		}
+	}
+
+	// Sort to get stable results
+	ret := []SelectedProvider{}
+	for _, pAddr := range slices.Sorted(maps.Keys(selected)) {
+		ret = append(ret, selected[pAddr])
+	}
+	return ret
+}
+
 // updateProviderBlock updates the provider block in the dependency lock file.
-func (u *LockUpdater) updateProviderBlock(ctx context.Context, pBlock *hclwrite.Block, p SelectedProvider) error {
+// Note that changes of hashes are not recorded because they are derived from
+// the version.
+// Unlike other updaters, it never skips downgrading, because the lock file
+// must be consistent with the configuration.
+func (u *LockUpdater) updateProviderBlock(ctx context.Context, mc *ModuleContext, filename string, pBlock *hclwrite.Block, p SelectedProvider) error {
+	vVal := ""
 	vAttr := pBlock.Body().GetAttribute("version")
 	if vAttr != nil {
 		// a version attribute found
-		vVal := getAttributeValueAsUnquotedString(vAttr)
+		vVal = getAttributeValueAsUnquotedString(vAttr)
 		log.Printf("[DEBUG] check provider version in lock file: address = %s, lock = %s, config = %s", p.Source, vVal, p.Version)
 		if vVal == p.Version {
-			// Avoid unnecessary recalculations if no version change
+			// Avoid unnecessary recalculations if no version change, but keep the
+			// constraints in sync with the configuration.
+			u.updateConstraints(mc, filename, pBlock, p)
 			return nil
 		}
 	}
 
-	pBlock.Body().SetAttributeValue("version", cty.StringVal(p.Version))
-
-	//Strictly speaking, constraints can contain multiple constraint expressions,
-	//including comparison operators, but in the tfupdate use case, we assume
-	//that the required_providers are pinned to a specific version to detect the
-	//required version without terraform init, so we can simply specify the
-	//constraints attribute as the same as the version. This may differ from what
-	//terraform generates, but we expect that it doesn't matter in practice.
-	pBlock.Body().SetAttributeValue("constraints", cty.StringVal(p.Version))
+	setAttributeValueAsString(pBlock.Body(), "version", p.Version)
+	mc.recordChange(newChange(filename, "provider", pBlock.Labels(), "version", vVal, p.Version))
+	u.updateConstraints(mc, filename, pBlock, p)
 
 	// Calculate the hash value of the provider.
-	// Note that the provider will be downloaded if cache miss.
-	pv, err := u.index.GetOrCreateProviderVersion(ctx, p.Source, p.Version, u.platforms)
+	// Note that the provider will be downloaded if cache miss, unless it is
+	// installed in the .terraform/providers directory of the module.
+	pluginDirs := []string{filepath.Join(mc.dir, ".terraform", "providers")}
+	pv, err := u.index.GetOrCreateProviderVersion(ctx, p.Source, p.Version, u.platforms, pluginDirs)
 	if err != nil {
 		return err
 	}
@@ -122,6 +406,88 @@ func (u *LockUpdater) updateProviderBlock(ctx context.Context, pBlock *hclwrite.
 	return nil
 }
 
+// updateConstraints updates the constraints attribute of the provider block
+// if it differs from the configuration.
+func (u *LockUpdater) updateConstraints(mc *ModuleContext, filename string, pBlock *hclwrite.Block, p SelectedProvider) {
+	cVal := ""
+	if cAttr := pBlock.Body().GetAttribute("constraints"); cAttr != nil {
+		cVal = getAttributeValueAsUnquotedString(cAttr)
+	}
+	if cVal == p.Constraints {
+		return
+	}
+
+	setAttributeValueAsString(pBlock.Body(), "constraints", p.Constraints)
+	mc.recordChange(newChange(filename, "provider", pBlock.Labels(), "constraints", cVal, p.Constraints))
+}
+
+// resolveProviderVersion resolves version constraints of the provider which
+// are not pinned to a specific version. It selects the newest available
+// version which satisfies them, like terraform init -upgrade, so the locked
+// version may be upgraded even if it still satisfies the constraints.
+func (u *LockUpdater) resolveProviderVersion(ctx context.Context, p SelectedProvider) (string, error) {
+	constraints, err := version.NewConstraint(p.Constraints)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse version constraints: %s: %s", p.Source, p.Constraints)
+	}
+
+	versions, err := u.listAvailableVersions(ctx, p.Source)
+	if err != nil {
+		return "", err
+	}
+
+	for _, v := range versions {
+		if constraints.Check(v) {
+			log.Printf("[DEBUG] select the newest version: address = %s, version = %s, constraints = %s", p.Source, v.String(), p.Constraints)
+			return v.String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("no available version of provider %s matches the constraints: %s", p.Source, p.Constraints)
+}
+
+// listAvailableVersions returns available versions of the provider sorted in
+// descending order. The result is cached in memory for each provider.
+func (u *LockUpdater) listAvailableVersions(ctx context.Context, address string) (version.Collection, error) {
+	u.mu.Lock()
+	versions, ok := u.availableVersions[address]
+	u.mu.Unlock()
+	if ok {
+		return versions, nil
+	}
+
+	pAddr, err := tfaddr.ParseProviderSource(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse provider address: %s", address)
+	}
+
+	req := &tfregistry.ListProviderVersionsRequest{
+		Namespace: pAddr.Namespace,
+		Type:      pAddr.Type,
+	}
+	res, err := u.registry.ListProviderVersions(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list provider versions: %s: %s", address, err)
+	}
+
+	versions = version.Collection{}
+	for _, pv := range res.Versions {
+		v, err := version.NewVersion(pv.Version)
+		if err != nil {
+			// Ignore parse error
+			log.Printf("[DEBUG] LockUpdater.listAvailableVersions: ignore version parse error: address = %s, version = %s", address, pv.Version)
+			continue
+		}
+		versions = append(versions, v)
+	}
+	sort.Sort(sort.Reverse(versions))
+
+	u.mu.Lock()
+	u.availableVersions[address] = versions
+	u.mu.Unlock()
+	return versions, nil
+}
+
 // fullyQualifiedProviderAddress converts the short form of the provider
 // address into the fully qualified form.
 // Example: hashicorp/null => registry.terraform.io/hashicorp/null
@@ -154,3 +520,237 @@ func (u *LockUpdater) fullyQualifiedProviderAddress(address string) (string, err
 
 	return pAddr.String(), nil
 }
+
+// createLockFile creates a dependency lock file in a given module if it has
+// pinned providers but no lock file yet. The lock file is populated in the
+// same way as updating an existing one.
+// The caller is responsible for skipping child modules.
+func createLockFile(ctx context.Context, mc *ModuleContext) error {
+	filename := filepath.Join(mc.dir, ".terraform.lock.hcl")
+	exists, err := afero.Exists(mc.FS(), filename)
+	if err != nil {
+		return fmt.Errorf("failed to check file: %s", err)
+	}
+
+	pinned := slices.ContainsFunc(mc.SelectedProviders(), func(p SelectedProvider) bool {
+		return p.Version != ""
+	})
+	if exists || !pinned {
+		return nil
+	}
+
+	log.Printf("[INFO] create file: %s", filename)
+	return mc.gc.keepGoing(filename, updateFile(ctx, mc, filename, strings.NewReader(lockFileHeader), true))
+}
+
+// LockIssue is an inconsistency between the configuration and the dependency
+// lock file.
+type LockIssue struct {
+	// Filename is a path of the dependency lock file.
+	Filename string `json:"filename"`
+
+	// Address is a fully qualified address of the provider.
+	// It is empty if the issue is not specific to a provider.
+	Address string `json:"address,omitempty"`
+
+	// Type is a type of the issue. Valid values are as follows:
+	// - missing_lock_file: The root module requires providers, but has no lock
+	//   file. Modules called as a local module by others are not checked.
+	// - missing_provider: The provider is not found in the lock file.
+	// - version_mismatch: The locked version differs from the configuration.
+	// - missing_hashes: The lock file has no hashes, or fewer h1 hashes than
+	//   the requested platforms. Note that it cannot tell which platforms are
+	//   missing.
+	Type string `json:"type"`
+
+	// Message is a human-readable description of the issue.
+	Message string `json:"message"`
+}
+
+// String returns a human-readable representation of the issue.
+func (i LockIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Filename, i.Message)
+}
+
+// VerifyLockFileOrDir checks whether dependency lock files in a given file or
+// directory are consistent with the configuration without updating them.
+// Unlike updating, it never calls the registry, so it is suitable for CI.
+// It returns a list of issues found. If the keepGoing option is set, errors
+// for files are recorded as failures in the GlobalContext and the rest of
+// files are still verified.
+func VerifyLockFileOrDir(gc *GlobalContext, path string) ([]LockIssue, error) {
+	u, ok := gc.updater.(*LockUpdater)
+	if !ok {
+		return nil, fmt.Errorf("failed to verify lock files. unexpected updater: %T", gc.updater)
+	}
+
+	childDirs, err := collectChildModuleDirs(gc, path)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []LockIssue{}
+	dirFn := func(mc *ModuleContext) error {
+		if childDirs[mc.dir] {
+			// A child module doesn't need a lock file.
+			return nil
+		}
+
+		filename := filepath.Join(mc.dir, ".terraform.lock.hcl")
+		exists, err := afero.Exists(mc.FS(), filename)
+		if err != nil {
+			return fmt.Errorf("failed to check file: %s", err)
+		}
+		if exists {
+			return nil
+		}
+
+		reqs, err := u.collectProviderRequirements(mc)
+		if err != nil {
+			log.Printf("[DEBUG] VerifyLockFileOrDir: ignore constraints in child modules: %s", err)
+		}
+		if len(u.selectProviders(mc, reqs)) > 0 {
+			issues = append(issues, LockIssue{
+				Filename: filename,
+				Type:     "missing_lock_file",
+				Message:  "lock file is not found",
+			})
+		}
+		return nil
+	}
+
+	err = walkFileOrDir(gc, path, dirFn, func(mc *ModuleContext, filename string) error {
+		if filepath.Base(filename) != ".terraform.lock.hcl" {
+			return nil
+		}
+
+		log.Printf("[DEBUG] verify file: %s", filename)
+		f, err := readLockfile(mc, filename)
+		if err != nil {
+			return mc.gc.keepGoing(filename, err)
+		}
+
+		issues = append(issues, u.verifyLockfile(mc, filename, f)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return issues, nil
+}
+
+// readLockfile reads and parses the dependency lock file.
+func readLockfile(mc *ModuleContext, filename string) (*hclwrite.File, error) {
+	input, err := afero.ReadFile(mc.FS(), filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %s", err)
+	}
+
+	return safeParseConfig(input, filename, hcl.Pos{Line: 1, Column: 1})
+}
+
+// verifyLockfile compares providers required by the module and its local
+// child modules with provider blocks in the dependency lock file.
+func (u *LockUpdater) verifyLockfile(mc *ModuleContext, filename string, f *hclwrite.File) []LockIssue {
+	reqs, err := u.collectProviderRequirements(mc)
+	if err != nil {
+		log.Printf("[DEBUG] LockUpdater.verifyLockfile: ignore constraints in child modules: %s", err)
+	}
+
+	issues := []LockIssue{}
+	for _, p := range u.selectProviders(mc, reqs) {
+		pAddr, err := u.fullyQualifiedProviderAddress(p.Source)
+		if err != nil {
+			log.Printf("[DEBUG] LockUpdater.verifyLockfile: ignore legacy provider address notation: %s", p.Source)
+			continue
+		}
+
+		pBlock := f.Body().FirstMatchingBlock("provider", []string{pAddr})
+		if pBlock == nil {
+			issues = append(issues, LockIssue{
+				Filename: filename,
+				Address:  pAddr,
+				Type:     "missing_provider",
+				Message:  fmt.Sprintf("provider %s is not found", pAddr),
+			})
+			continue
+		}
+
+		vVal := ""
+		if vAttr := pBlock.Body().GetAttribute("version"); vAttr != nil {
+			vVal = getAttributeValueAsUnquotedString(vAttr)
+		}
+		if !acceptsLockedVersion(p, vVal) {
+			required := p.Version
+			if required == "" {
+				required = p.Constraints
+			}
+			issues = append(issues, LockIssue{
+				Filename: filename,
+				Address:  pAddr,
+				Type:     "version_mismatch",
+				Message:  fmt.Sprintf("provider %s is locked to %q, but the configuration requires %q", pAddr, vVal, required),
+			})
+			continue
+		}
+
+		hashes := []string{}
+		if hAttr := pBlock.Body().GetAttribute("hashes"); hAttr != nil {
+			hashes = getAttributeValueAsStringList(hAttr)
+		}
+		if msg := u.verifyHashes(pAddr, hashes); msg != "" {
+			issues = append(issues, LockIssue{
+				Filename: filename,
+				Address:  pAddr,
+				Type:     "missing_hashes",
+				Message:  msg,
+			})
+		}
+	}
+
+	return issues
+}
+
+// acceptsLockedVersion returns true if the locked version satisfies the
+// version requirement of the provider in the configuration.
+func acceptsLockedVersion(p SelectedProvider, locked string) bool {
+	if p.Version != "" {
+		return locked == p.Version
+	}
+
+	v, err := version.NewVersion(locked)
+	if err != nil {
+		return false
+	}
+	constraints, err := version.NewConstraint(p.Constraints)
+	if err != nil {
+		return false
+	}
+	return constraints.Check(v)
+}
+
+// verifyHashes checks whether there are enough hashes for the requested
+// platforms. It returns a message if not enough, or otherwise returns an empty
+// string. The lock file doesn't record which platform each hash belongs to,
+// so we cannot verify them strictly without calling the registry. Instead, we
+// only compare the number of h1 hashes, which are calculated one per platform,
+// with the number of the requested platforms.
+func (u *LockUpdater) verifyHashes(pAddr string, hashes []string) string {
+	if len(hashes) == 0 {
+		return fmt.Sprintf("provider %s has no hashes", pAddr)
+	}
+
+	h1 := 0
+	for _, h := range hashes {
+		if strings.HasPrefix(h, "h1:") {
+			h1++
+		}
+	}
+
+	if h1 < len(u.platforms) {
+		return fmt.Sprintf("provider %s has fewer h1 hashes (%d) than the requested platforms (%d)", pAddr, h1, len(u.platforms))
+	}
+
+	return ""
+}
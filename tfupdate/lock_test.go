@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	version "github.com/hashicorp/go-version"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/minamijoyo/tfupdate/lock"
@@ -26,15 +27,16 @@ func TestNewLockUpdater(t *testing.T) {
 			platforms:        []string{"darwin_arm64", "darwin_amd64", "linux_amd64"},
 			tfregistryConfig: tfregistry.Config{},
 			want: &LockUpdater{
-				platforms:        []string{"darwin_arm64", "darwin_amd64", "linux_amd64"},
-				tfregistryConfig: tfregistry.Config{},
+				platforms:         []string{"darwin_arm64", "darwin_amd64", "linux_amd64"},
+				availableVersions: map[string]version.Collection{},
+				tfregistryConfig:  tfregistry.Config{},
 			},
 			ok: true,
 		},
 	}
 
 	for _, tc := range cases {
-		got, err := NewLockUpdater(tc.platforms, tc.tfregistryConfig)
+		got, err := NewLockUpdater(tc.platforms, tc.tfregistryConfig, "", nil, 0)
 		if tc.ok && err != nil {
 			t.Errorf("NewLockUpdater() with platforms = %#v returns unexpected err: %+v", tc.platforms, err)
 		}
@@ -51,7 +53,7 @@ func TestNewLockUpdater(t *testing.T) {
 			}
 
 			if diff := cmp.Diff(gotUpdater, tc.want,
-				cmpopts.IgnoreFields(LockUpdater{}, "index"),
+				cmpopts.IgnoreFields(LockUpdater{}, "index", "registry", "mu"),
 				cmp.AllowUnexported(LockUpdater{})); diff != "" {
 				t.Errorf("NewLockUpdater() with platforms = %s mismatch (-got +want):\n%s", tc.platforms, diff)
 			}
@@ -896,7 +898,59 @@ provider "registry.terraform.io/hashicorp/null" {
 			ok: true,
 		},
 		{
-			desc: "ignore unsupported constraints",
+			desc: "follow the configuration on downgrade",
+			src: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "3.2.1"
+    }
+  }
+}
+`,
+			lockfile: `
+# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.2"
+  constraints = "3.2.2"
+  hashes = [
+    "h1:zT1ZbegaAYHwQa+QwIFugArWikRJI9dqohj8xb0GY88=",
+  ]
+}
+`,
+			want: `
+# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "3.2.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+    "h1:tSj1mL6OQ8ILGqR2mDu7OYYYWf+hoir0pf9KAQ8IzO8=",
+    "h1:ydA0/SNRVB1o95btfshvYsmxA+jZFRZcvKzZSB+4S1M=",
+    "zh:58ed64389620cc7b82f01332e27723856422820cfd302e304b5f6c3436fb9840",
+    "zh:62a5cc82c3b2ddef7ef3a6f2fedb7b9b3deff4ab7b414938b08e51d6e8be87cb",
+    "zh:63cff4de03af983175a7e37e52d4bd89d990be256b16b5c7f919aff5ad485aa5",
+    "zh:74cb22c6700e48486b7cabefa10b33b801dfcab56f1a6ac9b6624531f3d36ea3",
+    "zh:78d5eefdd9e494defcb3c68d282b8f96630502cac21d1ea161f53cfe9bb483b3",
+    "zh:79e553aff77f1cfa9012a2218b8238dd672ea5e1b2924775ac9ac24d2a75c238",
+    "zh:a1e06ddda0b5ac48f7e7c7d59e1ab5a4073bbcf876c73c0299e4610ed53859dc",
+    "zh:c37a97090f1a82222925d45d84483b2aa702ef7ab66532af6cbcfb567818b970",
+    "zh:e4453fbebf90c53ca3323a92e7ca0f9961427d2f0ce0d2b65523cc04d5d999c2",
+    "zh:e80a746921946d8b6761e77305b752ad188da60688cfd2059322875d363be5f5",
+    "zh:fbdb892d9822ed0e4cb60f2fedbdbb556e4da0d88d3b942ae963ed6ff091e48f",
+    "zh:fca01a623d90d0cad0843102f9b8b9fe0d3ff8244593bd817f126582b52dd694",
+  ]
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "select the newest version which satisfies constraints",
 			src: `
 terraform {
   required_version = "1.5.0"
@@ -1105,11 +1159,12 @@ provider "registry.terraform.io/integrations/github" {
 			mockIndex := lock.NewMockIndex(pvs)
 
 			// Create a LockUpdater with empty tfregistryConfig
-			u, err := NewLockUpdater(platforms, tfregistry.Config{})
+			u, err := NewLockUpdater(platforms, tfregistry.Config{}, "", nil, 0)
 
-			// Replace the index with our mock for testing
+			// Replace the index and registry with our mock for testing
 			lu := u.(*LockUpdater)
 			lu.index = mockIndex
+			lu.registry = &mockProviderRegistry{versions: []string{"5.3.0", "5.4.0"}}
 			if err != nil {
 				t.Fatalf("failed to new LockUpdater: %s", err)
 			}
@@ -1319,7 +1374,7 @@ provider "registry.opentofu.org/hashicorp/null" {
 			mockIndex := lock.NewMockIndex(pvs)
 
 			// Create a LockUpdater with the tfregistryConfig
-			u, err := NewLockUpdater(platforms, tc.tfregistryConfig)
+			u, err := NewLockUpdater(platforms, tc.tfregistryConfig, "", nil, 0)
 
 			// Replace the index with our mock for testing
 			lu := u.(*LockUpdater)
@@ -1355,3 +1410,1193 @@ provider "registry.opentofu.org/hashicorp/null" {
 		})
 	}
 }
+
+func TestVerifyLockFileOrDir(t *testing.T) {
+	src := `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "5.4.0"
+    }
+
+    null = {
+      source  = "hashicorp/null"
+      version = "3.2.1"
+    }
+  }
+}
+`
+
+	cases := []struct {
+		desc      string
+		src       string
+		child     string
+		platforms []string
+		lockfile  string
+		want      []LockIssue
+	}{
+		{
+			desc:      "consistent",
+			platforms: []string{"darwin_arm64", "linux_amd64"},
+			lockfile: `
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.4.0"
+  constraints = "5.4.0"
+  hashes = [
+    "h1:4eGsUS3r5eApQc19t8woc6d+sQLaOBaCSaK5GyGcWf0=",
+    "h1:Jol4lNIzMrREQzUBSveCLX0iQLy7dm0OF+IYY2GKrhY=",
+    "zh:1db5f81089216831bb0fdff9ddc3772efa133397c66ec276bc75b96eec06e23f",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "3.2.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+    "h1:tSj1mL6OQ8ILGqR2mDu7OYYYWf+hoir0pf9KAQ8IzO8=",
+  ]
+}
+`,
+			want: []LockIssue{},
+		},
+		{
+			desc:      "inconsistent",
+			platforms: []string{"darwin_arm64", "linux_amd64"},
+			lockfile: `
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.1.1"
+  constraints = "3.1.1"
+  hashes = [
+    "h1:71sNUDvmiJcijsvfXpiLCz0lXIBSsEJjMxljt7hxMhw=",
+  ]
+}
+`,
+			want: []LockIssue{
+				{
+					Filename: "test/.terraform.lock.hcl",
+					Address:  "registry.terraform.io/hashicorp/aws",
+					Type:     "missing_provider",
+					Message:  "provider registry.terraform.io/hashicorp/aws is not found",
+				},
+				{
+					Filename: "test/.terraform.lock.hcl",
+					Address:  "registry.terraform.io/hashicorp/null",
+					Type:     "version_mismatch",
+					Message:  `provider registry.terraform.io/hashicorp/null is locked to "3.1.1", but the configuration requires "3.2.1"`,
+				},
+			},
+		},
+		{
+			desc:      "missing hashes",
+			platforms: []string{"darwin_arm64", "linux_amd64"},
+			lockfile: `
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.4.0"
+  constraints = "5.4.0"
+}
+
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "3.2.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+    "zh:58ed64389620cc7b82f01332e27723856422820cfd302e304b5f6c3436fb9840",
+  ]
+}
+`,
+			want: []LockIssue{
+				{
+					Filename: "test/.terraform.lock.hcl",
+					Address:  "registry.terraform.io/hashicorp/aws",
+					Type:     "missing_hashes",
+					Message:  "provider registry.terraform.io/hashicorp/aws has no hashes",
+				},
+				{
+					Filename: "test/.terraform.lock.hcl",
+					Address:  "registry.terraform.io/hashicorp/null",
+					Type:     "missing_hashes",
+					Message:  "provider registry.terraform.io/hashicorp/null has fewer h1 hashes (1) than the requested platforms (2)",
+				},
+			},
+		},
+		{
+			desc:      "missing lock file",
+			platforms: []string{"darwin_arm64", "linux_amd64"},
+			lockfile:  "",
+			want: []LockIssue{
+				{
+					Filename: "test/.terraform.lock.hcl",
+					Type:     "missing_lock_file",
+					Message:  "lock file is not found",
+				},
+			},
+		},
+		{
+			desc: "skip missing lock files in local child modules",
+			src: `
+module "child" {
+  source = "./modules/child"
+}
+`,
+			child: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "3.2.1"
+    }
+  }
+}
+`,
+			platforms: []string{"darwin_arm64"},
+			lockfile: `
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "3.2.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+  ]
+}
+`,
+			want: []LockIssue{},
+		},
+		{
+			desc: "missing lock file with providers required only by local child modules",
+			src: `
+module "child" {
+  source = "./modules/child"
+}
+`,
+			child: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "3.2.1"
+    }
+  }
+}
+`,
+			platforms: []string{"darwin_arm64"},
+			lockfile:  "",
+			want: []LockIssue{
+				{
+					Filename: "test/.terraform.lock.hcl",
+					Type:     "missing_lock_file",
+					Message:  "lock file is not found",
+				},
+			},
+		},
+		{
+			desc: "merge constraints of local child modules",
+			src: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "3.2.1"
+    }
+  }
+}
+
+module "child" {
+  source = "./modules/child"
+}
+`,
+			child: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "~> 3.1"
+    }
+
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+`,
+			platforms: []string{"darwin_arm64"},
+			lockfile: `
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "~> 3.1, 3.2.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+  ]
+}
+`,
+			want: []LockIssue{
+				{
+					Filename: "test/.terraform.lock.hcl",
+					Address:  "registry.terraform.io/hashicorp/aws",
+					Type:     "missing_provider",
+					Message:  "provider registry.terraform.io/hashicorp/aws is not found",
+				},
+			},
+		},
+		{
+			desc: "version mismatch with constraints of local child modules",
+			src: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "~> 3.1"
+    }
+  }
+}
+
+module "child" {
+  source = "./modules/child"
+}
+`,
+			child: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = ">= 3.2"
+    }
+  }
+}
+`,
+			platforms: []string{"darwin_arm64"},
+			lockfile: `
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.1.1"
+  constraints = "~> 3.1"
+  hashes = [
+    "h1:71sNUDvmiJcijsvfXpiLCz0lXIBSsEJjMxljt7hxMhw=",
+  ]
+}
+`,
+			want: []LockIssue{
+				{
+					Filename: "test/.terraform.lock.hcl",
+					Address:  "registry.terraform.io/hashicorp/null",
+					Type:     "version_mismatch",
+					Message:  `provider registry.terraform.io/hashicorp/null is locked to "3.1.1", but the configuration requires "~> 3.1, >= 3.2.0"`,
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			dirname := "test"
+			err := fs.MkdirAll(dirname, os.ModePerm)
+			if err != nil {
+				t.Fatalf("failed to create dir: %s", err)
+			}
+
+			mainSrc := src
+			if tc.src != "" {
+				mainSrc = tc.src
+			}
+			err = afero.WriteFile(fs, filepath.Join(dirname, "main.tf"), []byte(mainSrc), 0644)
+			if err != nil {
+				t.Fatalf("failed to write file: %s", err)
+			}
+
+			if tc.child != "" {
+				childDir := filepath.Join(dirname, "modules", "child")
+				if err := fs.MkdirAll(childDir, os.ModePerm); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := afero.WriteFile(fs, filepath.Join(childDir, "main.tf"), []byte(tc.child), 0644); err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			if tc.lockfile != "" {
+				err = afero.WriteFile(fs, filepath.Join(dirname, ".terraform.lock.hcl"), []byte(tc.lockfile), 0644)
+				if err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			o := Option{
+				updateType: "lock",
+				platforms:  tc.platforms,
+				recursive:  true,
+			}
+			gc, err := NewGlobalContext(fs, o)
+			if err != nil {
+				t.Fatalf("failed to new global context: %s", err)
+			}
+
+			got, err := VerifyLockFileOrDir(gc, dirname)
+			if err != nil {
+				t.Fatalf("failed to verify lock files: %s", err)
+			}
+
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("got: %#v, want = %#v, diff = %s", got, tc.want, diff)
+			}
+		})
+	}
+}
+
+func TestVerifyLockFileOrDirKeepGoing(t *testing.T) {
+	files := map[string]string{
+		"test/a/.terraform.lock.hcl": "provider {\n",
+		"test/b/.terraform.lock.hcl": `
+provider "registry.terraform.io/hashicorp/null" {
+  version = "3.2.1"
+}
+`,
+		"test/b/main.tf": `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "3.2.1"
+    }
+  }
+}
+`,
+	}
+
+	cases := []struct {
+		desc      string
+		keepGoing bool
+		ok        bool
+		failures  []string
+		issues    int
+	}{
+		{
+			desc:      "stop",
+			keepGoing: false,
+			ok:        false,
+		},
+		{
+			desc:      "keep going",
+			keepGoing: true,
+			ok:        true,
+			failures:  []string{"test/a/.terraform.lock.hcl"},
+			issues:    1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for filename, src := range files {
+				if err := fs.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := afero.WriteFile(fs, filename, []byte(src), 0644); err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			o := Option{
+				updateType: "lock",
+				platforms:  []string{"linux_amd64"},
+				recursive:  true,
+				keepGoing:  tc.keepGoing,
+			}
+			gc, err := NewGlobalContext(fs, o)
+			if err != nil {
+				t.Fatalf("failed to new global context: %s", err)
+			}
+
+			issues, err := VerifyLockFileOrDir(gc, "test")
+			if tc.ok && err != nil {
+				t.Fatalf("failed to verify lock files: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("expected to return an error, but no error")
+				}
+				return
+			}
+
+			gotFailures := []string{}
+			for _, f := range gc.Failures() {
+				gotFailures = append(gotFailures, f.Filename)
+			}
+			if diff := cmp.Diff(gotFailures, tc.failures); diff != "" {
+				t.Errorf("got: %#v, want = %#v, diff = %s", gotFailures, tc.failures, diff)
+			}
+
+			// The rest of lock files are still verified.
+			if len(issues) != tc.issues {
+				t.Errorf("got %d issues, want = %d: %#v", len(issues), tc.issues, issues)
+			}
+		})
+	}
+}
+
+func TestAcceptsLockedVersion(t *testing.T) {
+	cases := []struct {
+		desc   string
+		p      SelectedProvider
+		locked string
+		want   bool
+	}{
+		{
+			desc:   "pinned version matches",
+			p:      SelectedProvider{Source: "hashicorp/null", Version: "3.2.1", Constraints: "3.2.1"},
+			locked: "3.2.1",
+			want:   true,
+		},
+		{
+			desc:   "pinned version mismatches",
+			p:      SelectedProvider{Source: "hashicorp/null", Version: "3.2.1", Constraints: "3.2.1"},
+			locked: "3.1.1",
+			want:   false,
+		},
+		{
+			desc:   "constraints satisfied",
+			p:      SelectedProvider{Source: "hashicorp/null", Version: "", Constraints: ">= 3.0, < 4.0"},
+			locked: "3.1.1",
+			want:   true,
+		},
+		{
+			desc:   "constraints not satisfied",
+			p:      SelectedProvider{Source: "hashicorp/null", Version: "", Constraints: "~> 3.2"},
+			locked: "3.1.1",
+			want:   false,
+		},
+		{
+			desc:   "not locked",
+			p:      SelectedProvider{Source: "hashicorp/null", Version: "", Constraints: "~> 3.2"},
+			locked: "",
+			want:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := acceptsLockedVersion(tc.p, tc.locked)
+			if got != tc.want {
+				t.Errorf("got=%t, but want=%t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUpdateLockPrune(t *testing.T) {
+	lockfile := `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.4.0"
+  constraints = "5.4.0"
+  hashes = [
+    "h1:4eGsUS3r5eApQc19t8woc6d+sQLaOBaCSaK5GyGcWf0=",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "3.2.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+  ]
+}
+
+provider "registry.terraform.io/integrations/github" {
+  version     = "4.28.0"
+  constraints = "4.28.0"
+  hashes = [
+    "h1:z+hNGx6GIlk2dYPaP6R4wJ1pMa0ydN1DmE8L0xV24Ts=",
+  ]
+}
+`
+
+	cases := []struct {
+		desc         string
+		files        map[string]string
+		prune        bool
+		want         string
+		wantWarnings []string
+	}{
+		{
+			desc: "prune",
+			files: map[string]string{
+				"test/main.tf": `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "5.4.0"
+    }
+  }
+}
+
+resource "null_resource" "foo" {}
+`,
+			},
+			prune: true,
+			want: `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.4.0"
+  constraints = "5.4.0"
+  hashes = [
+    "h1:4eGsUS3r5eApQc19t8woc6d+sQLaOBaCSaK5GyGcWf0=",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "3.2.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+  ]
+}
+`,
+			wantWarnings: nil,
+		},
+		{
+			desc: "keep providers required by local modules",
+			files: map[string]string{
+				"test/main.tf": `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "5.4.0"
+    }
+  }
+}
+
+module "child" {
+  source = "./child"
+}
+`,
+				"test/child/main.tf": `
+terraform {
+  required_providers {
+    github = {
+      source = "integrations/github"
+    }
+  }
+}
+`,
+			},
+			prune: true,
+			want: `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.4.0"
+  constraints = "5.4.0"
+  hashes = [
+    "h1:4eGsUS3r5eApQc19t8woc6d+sQLaOBaCSaK5GyGcWf0=",
+  ]
+}
+
+provider "registry.terraform.io/integrations/github" {
+  version     = "4.28.0"
+  constraints = "4.28.0"
+  hashes = [
+    "h1:z+hNGx6GIlk2dYPaP6R4wJ1pMa0ydN1DmE8L0xV24Ts=",
+  ]
+}
+`,
+			wantWarnings: nil,
+		},
+		{
+			desc: "skip remote modules",
+			files: map[string]string{
+				"test/main.tf": `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "5.4.0"
+    }
+  }
+}
+
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.1.0"
+}
+`,
+			},
+			prune: true,
+			want:  lockfile,
+			wantWarnings: []string{
+				"test/.terraform.lock.hcl: skip pruning provider blocks: remote module is not supported: terraform-aws-modules/vpc/aws",
+			},
+		},
+		{
+			desc: "no prune",
+			files: map[string]string{
+				"test/main.tf": `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "5.4.0"
+    }
+  }
+}
+`,
+			},
+			prune:        false,
+			want:         lockfile,
+			wantWarnings: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			files := map[string]string{"test/.terraform.lock.hcl": lockfile}
+			for filename, src := range tc.files {
+				files[filename] = src
+			}
+			for filename, src := range files {
+				if err := fs.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := afero.WriteFile(fs, filename, []byte(src), 0644); err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			o := Option{
+				updateType: "lock",
+				platforms:  []string{"linux_amd64"},
+				prune:      tc.prune,
+			}
+			gc, err := NewGlobalContext(fs, o)
+			if err != nil {
+				t.Fatalf("failed to new global context: %s", err)
+			}
+
+			// The versions in the lock file are up to date, so the registry is not called.
+			err = UpdateFileOrDir(context.Background(), gc, "test")
+			if err != nil {
+				t.Fatalf("failed to update lock file: %s", err)
+			}
+
+			got, err := afero.ReadFile(fs, "test/.terraform.lock.hcl")
+			if err != nil {
+				t.Fatalf("failed to read updated file: %s", err)
+			}
+
+			if diff := cmp.Diff(string(got), tc.want); diff != "" {
+				t.Errorf("got: %s, want = %s, diff = %s", string(got), tc.want, diff)
+			}
+
+			if diff := cmp.Diff(gc.Warnings(), tc.wantWarnings); diff != "" {
+				t.Errorf("got warnings: %#v, want = %#v, diff = %s", gc.Warnings(), tc.wantWarnings, diff)
+			}
+		})
+	}
+}
+
+func TestUpdateLockCreate(t *testing.T) {
+	pvs := []*lock.ProviderVersion{
+		lock.NewMockProviderVersion(
+			"hashicorp/null",
+			"3.2.1",
+			[]string{"linux_amd64"},
+			map[string]string{
+				"terraform-provider-null_3.2.1_linux_amd64.zip": "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+			},
+			map[string]string{
+				"terraform-provider-null_3.2.1_linux_amd64.zip": "zh:74cb22c6700e48486b7cabefa10b33b801dfcab56f1a6ac9b6624531f3d36ea3",
+			},
+		),
+	}
+
+	pinned := `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "3.2.1"
+    }
+  }
+}
+`
+
+	cases := []struct {
+		desc   string
+		files  map[string]string
+		create bool
+		want   map[string]string
+	}{
+		{
+			desc: "create",
+			files: map[string]string{
+				"test/main.tf": pinned,
+				"test/modules/child/main.tf": `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = ">= 3.0"
+    }
+  }
+}
+`,
+			},
+			create: true,
+			want: map[string]string{
+				"test/.terraform.lock.hcl": `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "3.2.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+    "zh:74cb22c6700e48486b7cabefa10b33b801dfcab56f1a6ac9b6624531f3d36ea3",
+  ]
+}
+`,
+				"test/modules/child/.terraform.lock.hcl": "",
+			},
+		},
+		{
+			desc: "skip child modules",
+			files: map[string]string{
+				"test/main.tf": pinned + `
+module "child" {
+  source = "./modules/child"
+}
+`,
+				"test/modules/child/main.tf": pinned,
+			},
+			create: true,
+			want: map[string]string{
+				"test/.terraform.lock.hcl": `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "3.2.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+    "zh:74cb22c6700e48486b7cabefa10b33b801dfcab56f1a6ac9b6624531f3d36ea3",
+  ]
+}
+`,
+				"test/modules/child/.terraform.lock.hcl": "",
+			},
+		},
+		{
+			desc: "no create",
+			files: map[string]string{
+				"test/main.tf": pinned,
+			},
+			create: false,
+			want: map[string]string{
+				"test/.terraform.lock.hcl": "",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for filename, src := range tc.files {
+				if err := fs.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := afero.WriteFile(fs, filename, []byte(src), 0644); err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			o := Option{
+				updateType:     "lock",
+				platforms:      []string{"linux_amd64"},
+				recursive:      true,
+				createLockFile: tc.create,
+			}
+			gc, err := NewGlobalContext(fs, o)
+			if err != nil {
+				t.Fatalf("failed to new global context: %s", err)
+			}
+
+			// Replace the index with our mock for testing
+			gc.updater.(*LockUpdater).index = lock.NewMockIndex(pvs)
+
+			err = UpdateFileOrDir(context.Background(), gc, "test")
+			if err != nil {
+				t.Fatalf("failed to update lock file: %s", err)
+			}
+
+			// Only created files are updated in this test.
+			for _, r := range gc.Results() {
+				if len(r.Original) != 0 {
+					t.Errorf("%s should be recorded as a new file, but got original: %s", r.Filename, string(r.Original))
+				}
+			}
+
+			for filename, want := range tc.want {
+				got, err := afero.ReadFile(fs, filename)
+				if want == "" {
+					// The file should not be created.
+					if err == nil {
+						t.Errorf("%s should not be created, but got: %s", filename, string(got))
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("failed to read file: %s", err)
+				}
+
+				if diff := cmp.Diff(string(got), want); diff != "" {
+					t.Errorf("got: %s, want = %s, diff = %s", string(got), want, diff)
+				}
+			}
+		})
+	}
+}
+
+// mockProviderRegistry is a mock implementation of tfregistry.ProviderV1API
+// which returns preset available versions.
+type mockProviderRegistry struct {
+	versions []string
+}
+
+var _ tfregistry.ProviderV1API = (*mockProviderRegistry)(nil)
+
+func (r *mockProviderRegistry) ListProviderVersions(_ context.Context, _ *tfregistry.ListProviderVersionsRequest) (*tfregistry.ListProviderVersionsResponse, error) {
+	res := &tfregistry.ListProviderVersionsResponse{}
+	for _, v := range r.versions {
+		res.Versions = append(res.Versions, tfregistry.ProviderVersion{Version: v})
+	}
+	return res, nil
+}
+
+func (r *mockProviderRegistry) ProviderPackageMetadata(_ context.Context, _ *tfregistry.ProviderPackageMetadataRequest) (*tfregistry.ProviderPackageMetadataResponse, error) {
+	return nil, nil // dummy implementation as it's not used in tests
+}
+
+func TestUpdateLockWithVersionConstraints(t *testing.T) {
+	platforms := []string{"linux_amd64"}
+	pvs := []*lock.ProviderVersion{
+		lock.NewMockProviderVersion(
+			"hashicorp/null",
+			"3.2.1",
+			platforms,
+			map[string]string{
+				"terraform-provider-null_3.2.1_linux_amd64.zip": "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+			},
+			map[string]string{
+				"terraform-provider-null_3.2.1_linux_amd64.zip": "zh:74cb22c6700e48486b7cabefa10b33b801dfcab56f1a6ac9b6624531f3d36ea3",
+			},
+		),
+	}
+	versions := []string{"3.1.0", "3.1.1", "3.2.1", "3.3.0-beta1", "4.0.0"}
+
+	cases := []struct {
+		desc     string
+		src      string
+		child    string
+		lockfile string
+		want     string
+		ok       bool
+	}{
+		{
+			desc: "select the newest version",
+			src: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "~> 3.1"
+    }
+  }
+}
+`,
+			lockfile: `
+`,
+			want: `
+
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "~> 3.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+    "zh:74cb22c6700e48486b7cabefa10b33b801dfcab56f1a6ac9b6624531f3d36ea3",
+  ]
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "upgrade the locked version which satisfies constraints",
+			src: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "~> 3.1"
+    }
+  }
+}
+`,
+			lockfile: `
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.1.1"
+  constraints = "~> 3.1"
+  hashes = [
+    "h1:YvH6gTaQzGdNv+SKTZujU1O0bO+Pw6vJHOPhqgN8XNs=",
+    "zh:78d5eefdd9e494defcb3c68d282b8f96630502cac21d1ea161f53cfe9bb483b3",
+  ]
+}
+`,
+			want: `
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "~> 3.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+    "zh:74cb22c6700e48486b7cabefa10b33b801dfcab56f1a6ac9b6624531f3d36ea3",
+  ]
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "the locked version does not satisfy constraints",
+			src: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = ">= 3.2, < 4.0"
+    }
+  }
+}
+`,
+			lockfile: `
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.1.1"
+  constraints = "~> 3.1"
+  hashes = [
+    "h1:YvH6gTaQzGdNv+SKTZujU1O0bO+Pw6vJHOPhqgN8XNs=",
+    "zh:78d5eefdd9e494defcb3c68d282b8f96630502cac21d1ea161f53cfe9bb483b3",
+  ]
+}
+`,
+			want: `
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = ">= 3.2.0, < 4.0.0"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+    "zh:74cb22c6700e48486b7cabefa10b33b801dfcab56f1a6ac9b6624531f3d36ea3",
+  ]
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "merge constraints of local child modules",
+			src: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "3.2.1"
+    }
+  }
+}
+
+module "child" {
+  source = "./modules/child"
+}
+`,
+			child: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "~> 3.1"
+    }
+  }
+}
+`,
+			lockfile: `
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.1.1"
+  constraints = "3.1.1"
+  hashes = [
+    "h1:YvH6gTaQzGdNv+SKTZujU1O0bO+Pw6vJHOPhqgN8XNs=",
+    "zh:78d5eefdd9e494defcb3c68d282b8f96630502cac21d1ea161f53cfe9bb483b3",
+  ]
+}
+`,
+			want: `
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "~> 3.1, 3.2.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+    "zh:74cb22c6700e48486b7cabefa10b33b801dfcab56f1a6ac9b6624531f3d36ea3",
+  ]
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "add providers required only by local child modules",
+			src: `
+module "child" {
+  source = "./modules/child"
+}
+`,
+			child: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = "~> 3.1"
+    }
+  }
+}
+`,
+			lockfile: `
+`,
+			want: `
+
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "~> 3.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+    "zh:74cb22c6700e48486b7cabefa10b33b801dfcab56f1a6ac9b6624531f3d36ea3",
+  ]
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "update constraints without version change",
+			src: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = ">= 3.1, < 4.0"
+    }
+  }
+}
+`,
+			lockfile: `
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "~> 3.1"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+    "zh:74cb22c6700e48486b7cabefa10b33b801dfcab56f1a6ac9b6624531f3d36ea3",
+  ]
+}
+`,
+			want: `
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = ">= 3.1.0, < 4.0.0"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+    "zh:74cb22c6700e48486b7cabefa10b33b801dfcab56f1a6ac9b6624531f3d36ea3",
+  ]
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "no matching version",
+			src: `
+terraform {
+  required_providers {
+    null = {
+      source  = "hashicorp/null"
+      version = ">= 5.0"
+    }
+  }
+}
+`,
+			lockfile: `
+`,
+			want: `
+`,
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			dirname := "test"
+			err := fs.MkdirAll(dirname, os.ModePerm)
+			if err != nil {
+				t.Fatalf("failed to create dir: %s", err)
+			}
+
+			err = afero.WriteFile(fs, filepath.Join(dirname, "main.tf"), []byte(tc.src), 0644)
+			if err != nil {
+				t.Fatalf("failed to write file: %s", err)
+			}
+
+			if tc.child != "" {
+				childDir := filepath.Join(dirname, "modules", "child")
+				if err := fs.MkdirAll(childDir, os.ModePerm); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := afero.WriteFile(fs, filepath.Join(childDir, "main.tf"), []byte(tc.child), 0644); err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			o := Option{
+				updateType: "lock",
+				platforms:  platforms,
+			}
+			gc, err := NewGlobalContext(fs, o)
+			if err != nil {
+				t.Fatalf("failed to new global context: %s", err)
+			}
+
+			// Replace the index and registry with our mock for testing
+			u := gc.updater.(*LockUpdater)
+			u.index = lock.NewMockIndex(pvs)
+			u.registry = &mockProviderRegistry{versions: versions}
+
+			f, diags := hclwrite.ParseConfig([]byte(tc.lockfile), ".terraform.lock.hcl", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags)
+			}
+
+			mc, err := NewModuleContext(dirname, gc)
+			if err != nil {
+				t.Fatalf("failed to new module context: %s", err)
+			}
+
+			err = u.Update(context.Background(), mc, ".terraform.lock.hcl", f)
+			if tc.ok && err != nil {
+				t.Errorf("failed to call Update: err = %s", err)
+			}
+
+			got := string(hclwrite.Format(f.BuildTokens(nil).Bytes()))
+
+			if !tc.ok && err == nil {
+				t.Errorf("expect to fail, but success: got = %s", got)
+			}
+
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("got: %s, want = %s, diff = %s", got, tc.want, diff)
+			}
+		})
+	}
+}
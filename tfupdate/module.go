@@ -4,11 +4,11 @@ import (
 	"context"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/pkg/errors"
-	"github.com/zclconf/go-cty/cty"
 )
 
 // moduleSourceRegexp is a regular expression for module source.
@@ -44,13 +44,17 @@ func NewModuleUpdater(name string, version string, nameRegex *regexp.Regexp) (Up
 
 // Update updates the module version constraint.
 // Note that this method will rewrite the AST passed as an argument.
-func (u *ModuleUpdater) Update(_ context.Context, _ *ModuleContext, filename string, f *hclwrite.File) error {
+func (u *ModuleUpdater) Update(_ context.Context, mc *ModuleContext, filename string, f *hclwrite.File) error {
 	if filepath.Base(filename) == ".terraform.lock.hcl" {
 		// skip a lock file.
 		return nil
 	}
 
-	return u.updateModuleBlock(f)
+	if isTerragruntFile(filename) {
+		return u.updateTerragruntBlock(mc, filename, f)
+	}
+
+	return u.updateModuleBlock(mc, filename, f)
 }
 
 func (u *ModuleUpdater) match(name string) bool {
@@ -60,26 +64,102 @@ func (u *ModuleUpdater) match(name string) bool {
 	return u.nameRegex.MatchString(name)
 }
 
-func (u *ModuleUpdater) updateModuleBlock(f *hclwrite.File) error {
+// matchSource returns true if a given module source is a target module.
+// A target module matches either with or without the subdirectory such as
+// //modules/vpc.
+func (u *ModuleUpdater) matchSource(name string) bool {
+	address, _ := splitModuleSubdir(name)
+	return u.match(name) || u.match(address)
+}
+
+func (u *ModuleUpdater) updateModuleBlock(mc *ModuleContext, filename string, f *hclwrite.File) error {
 	for _, m := range allMatchingBlocksByType(f.Body(), "module") {
 		if s := m.Body().GetAttribute("source"); s != nil {
 			name, version := parseModuleSource(s)
 			// If this module is a target module
-			if u.match(name) {
+			if u.matchSource(name) {
 				if len(version) == 0 {
 					// The source attribute doesn't have a version number.
 					// Set a version to attribute value only if the version key exists.
-					if m.Body().GetAttribute("version") != nil {
-						m.Body().SetAttributeValue("version", cty.StringVal(u.version))
+					if v := m.Body().GetAttribute("version"); v != nil {
+						oldVersion := getAttributeValueAsUnquotedString(v)
+						newVersion := mc.resolveVersion(filename, oldVersion, u.version)
+						setAttributeValueAsString(m.Body(), "version", newVersion)
+						mc.recordChange(newChange(filename, "module", m.Labels(), "version", oldVersion, newVersion))
 					}
 					continue
 				}
 				// The source attribute has a version number.
 				// Update a version reference in the source value.
-				newSourceValue := name + `?ref=v` + u.version
-				m.Body().SetAttributeValue("source", cty.StringVal(newSourceValue))
+				oldSourceValue := getAttributeValueAsUnquotedString(s)
+				newSourceValue := name + `?ref=v` + mc.resolveVersion(filename, version, u.version)
+				setAttributeValueAsString(m.Body(), "source", newSourceValue)
+				mc.recordChange(newChange(filename, "module", m.Labels(), "source", oldSourceValue, newSourceValue))
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateTerragruntBlock updates a version reference in the source attribute of
+// the terraform block in the Terragrunt configuration file.
+// e.g. source = "git::https://example.com/modules.git//vpc?ref=v1.2.3"
+func (u *ModuleUpdater) updateTerragruntBlock(mc *ModuleContext, filename string, f *hclwrite.File) error {
+	for _, tf := range allMatchingBlocks(f.Body(), "terraform", []string{}) {
+		s := tf.Body().GetAttribute("source")
+		if s == nil {
+			continue
+		}
+		name, version := parseModuleSource(s)
+		if len(version) == 0 {
+			// Terragrunt doesn't have a version attribute, so there is nothing to
+			// update without a version reference in the source value.
+			continue
+		}
+		// If this module is a target module
+		if !u.matchSource(name) {
+			continue
+		}
+		oldSourceValue := getAttributeValueAsUnquotedString(s)
+		newSourceValue := name + `?ref=v` + mc.resolveVersion(filename, version, u.version)
+		setAttributeValueAsString(tf.Body(), "source", newSourceValue)
+		mc.recordChange(newChange(filename, "terraform", nil, "source", oldSourceValue, newSourceValue))
+	}
+
+	return nil
+}
+
+// updateJSON updates the module version constraint in the JSON syntax.
+func (u *ModuleUpdater) updateJSON(_ context.Context, mc *ModuleContext, filename string, f *jsonFile) error {
+	for _, m := range allMatchingJSONLabeledBlocks(f.body, "module") {
+		s := f.getString(m.body, "source")
+		if s == nil {
+			continue
+		}
+		name, version := parseModuleSourceString(s.str)
+		// If this module is a target module
+		if !u.matchSource(name) {
+			continue
+		}
+		labels := []string{m.label}
+		if len(version) == 0 {
+			// The source attribute doesn't have a version number.
+			// Set a version to attribute value only if the version key exists.
+			if v := f.getString(m.body, "version"); v != nil {
+				oldVersion := v.str
+				newVersion := mc.resolveVersion(filename, oldVersion, u.version)
+				f.setString(v, newVersion)
+				mc.recordChange(newChange(filename, "module", labels, "version", oldVersion, newVersion))
 			}
+			continue
 		}
+		// The source attribute has a version number.
+		// Update a version reference in the source value.
+		oldSourceValue := s.str
+		newSourceValue := name + `?ref=v` + mc.resolveVersion(filename, version, u.version)
+		f.setString(s, newSourceValue)
+		mc.recordChange(newChange(filename, "module", labels, "source", oldSourceValue, newSourceValue))
 	}
 
 	return nil
@@ -92,15 +172,36 @@ func parseModuleSource(a *hclwrite.Attribute) (string, string) {
 		tokens[0].Type == hclsyntax.TokenOQuote &&
 		tokens[1].Type == hclsyntax.TokenQuotedLit &&
 		tokens[2].Type == hclsyntax.TokenCQuote {
-		source := string(tokens[1].Bytes)
-		matched := moduleSourceRegexp.FindStringSubmatch(source)
-		if len(matched) == 0 {
-			// no version number
-			return source, ""
-		}
-		name := matched[1]
-		version := matched[2]
-		return name, version
+		return parseModuleSourceString(string(tokens[1].Bytes))
 	}
 	return "", ""
 }
+
+// parseModuleSourceString parses a string of module source and returns module
+// name and version.
+func parseModuleSourceString(source string) (string, string) {
+	matched := moduleSourceRegexp.FindStringSubmatch(source)
+	if len(matched) == 0 {
+		// no version number
+		return source, ""
+	}
+	name := matched[1]
+	version := matched[2]
+	return name, version
+}
+
+// splitModuleSubdir splits a module source into a package address and a
+// subdirectory such as //modules/vpc. Note that a double slash of the URL
+// scheme such as https:// is not a separator of the subdirectory.
+func splitModuleSubdir(source string) (string, string) {
+	offset := 0
+	if i := strings.Index(source, "://"); i >= 0 {
+		offset = i + len("://")
+	}
+
+	i := strings.Index(source[offset:], "//")
+	if i < 0 {
+		return source, ""
+	}
+	return source[:offset+i], source[offset+i:]
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/afero"
 )
 
 func TestNewModuleUpdater(t *testing.T) {
@@ -221,6 +222,155 @@ module "vpc2" {
   source  = "terraform-aws-modules.git/vpc/aws2"
   version = "2.18.0"
 }
+`,
+			ok: true,
+		},
+		{
+			filename: "main.tf",
+			src: `
+module "vpc_endpoints" {
+  source = "git::https://github.com/terraform-aws-modules/terraform-aws-vpc.git//modules/vpc-endpoints?ref=v5.0.0"
+}
+
+module "vpc_endpoints_registry" {
+  source  = "terraform-aws-modules/vpc/aws//modules/vpc-endpoints"
+  version = "5.0.0"
+}
+`,
+			name:            "git::https://github.com/terraform-aws-modules/terraform-aws-vpc.git",
+			version:         "5.1.0",
+			sourceMatchType: "full",
+			want: `
+module "vpc_endpoints" {
+  source = "git::https://github.com/terraform-aws-modules/terraform-aws-vpc.git//modules/vpc-endpoints?ref=v5.1.0"
+}
+
+module "vpc_endpoints_registry" {
+  source  = "terraform-aws-modules/vpc/aws//modules/vpc-endpoints"
+  version = "5.0.0"
+}
+`,
+			ok: true,
+		},
+		{
+			filename: "main.tf",
+			src: `
+module "vpc_endpoints" {
+  source  = "terraform-aws-modules/vpc/aws//modules/vpc-endpoints"
+  version = "5.0.0"
+}
+
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.0.0"
+}
+`,
+			name:            "terraform-aws-modules/vpc/aws",
+			version:         "5.1.0",
+			sourceMatchType: "full",
+			want: `
+module "vpc_endpoints" {
+  source  = "terraform-aws-modules/vpc/aws//modules/vpc-endpoints"
+  version = "5.1.0"
+}
+
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.1.0"
+}
+`,
+			ok: true,
+		},
+		{
+			filename: "terragrunt.hcl",
+			src: `
+terraform {
+  source = "git::https://example.com/modules.git//vpc?ref=v1.2.3"
+}
+
+inputs = {
+  name = "foo"
+}
+`,
+			name:            "git::https://example.com/modules.git",
+			version:         "1.3.0",
+			sourceMatchType: "full",
+			want: `
+terraform {
+  source = "git::https://example.com/modules.git//vpc?ref=v1.3.0"
+}
+
+inputs = {
+  name = "foo"
+}
+`,
+			ok: true,
+		},
+		{
+			filename: "live/prod/terragrunt.hcl",
+			src: `
+terraform {
+  source = "git@github.com:example/modules.git//vpc/aws?ref=v1.2.3"
+}
+`,
+			name:            "git@github.com:example/modules.git//vpc/aws",
+			version:         "1.3.0",
+			sourceMatchType: "full",
+			want: `
+terraform {
+  source = "git@github.com:example/modules.git//vpc/aws?ref=v1.3.0"
+}
+`,
+			ok: true,
+		},
+		{
+			filename: "terragrunt.hcl",
+			src: `
+terraform {
+  source = "git::https://example.com/modules.git//vpc?ref=v1.2.3"
+}
+`,
+			name:            "git::https://example.com/modules.git//ecs",
+			version:         "1.3.0",
+			sourceMatchType: "full",
+			want: `
+terraform {
+  source = "git::https://example.com/modules.git//vpc?ref=v1.2.3"
+}
+`,
+			ok: true,
+		},
+		{
+			filename: "terragrunt.hcl",
+			src: `
+terraform {
+  source = "git::https://example.com/modules.git//vpc?ref=main"
+}
+`,
+			name:            "git::https://example.com/modules.git",
+			version:         "1.3.0",
+			sourceMatchType: "full",
+			want: `
+terraform {
+  source = "git::https://example.com/modules.git//vpc?ref=main"
+}
+`,
+			ok: true,
+		},
+		{
+			filename: "terragrunt.hcl",
+			src: `
+terraform {
+  source = "${local.base_url}//vpc?ref=v1.2.3"
+}
+`,
+			name:            `.*`,
+			version:         "1.3.0",
+			sourceMatchType: "regex",
+			want: `
+terraform {
+  source = "${local.base_url}//vpc?ref=v1.2.3"
+}
 `,
 			ok: true,
 		},
@@ -242,7 +392,17 @@ module "vpc2" {
 			t.Fatalf("unexpected diagnostics: %s", diags)
 		}
 
-		err := u.Update(context.Background(), nil, tc.filename, f)
+		gc, err := NewGlobalContext(afero.NewMemMapFs(), Option{updateType: "module", name: tc.name, version: tc.version})
+		if err != nil {
+			t.Fatalf("failed to new global context: %s", err)
+		}
+
+		mc, err := NewModuleContext(".", gc)
+		if err != nil {
+			t.Fatalf("failed to new module context: %s", err)
+		}
+
+		err = u.Update(context.Background(), mc, tc.filename, f)
 		if tc.ok && err != nil {
 			t.Errorf("Update() with src = %s, name = %s, version = %s returns unexpected err: %+v", tc.src, tc.name, tc.version, err)
 		}
@@ -340,3 +500,44 @@ module "vpc" {
 		}
 	}
 }
+
+func TestSplitModuleSubdir(t *testing.T) {
+	cases := []struct {
+		source  string
+		address string
+		subdir  string
+	}{
+		{
+			source:  "terraform-aws-modules/vpc/aws",
+			address: "terraform-aws-modules/vpc/aws",
+			subdir:  "",
+		},
+		{
+			source:  "git::https://example.com/modules.git",
+			address: "git::https://example.com/modules.git",
+			subdir:  "",
+		},
+		{
+			source:  "git::https://example.com/modules.git//vpc",
+			address: "git::https://example.com/modules.git",
+			subdir:  "//vpc",
+		},
+		{
+			source:  "git@github.com:example/modules.git//vpc/aws",
+			address: "git@github.com:example/modules.git",
+			subdir:  "//vpc/aws",
+		},
+		{
+			source:  "github.com/example/modules//vpc",
+			address: "github.com/example/modules",
+			subdir:  "//vpc",
+		},
+	}
+
+	for _, tc := range cases {
+		address, subdir := splitModuleSubdir(tc.source)
+		if address != tc.address || subdir != tc.subdir {
+			t.Errorf("splitModuleSubdir() with source = %s returns (%s, %s), but want = (%s, %s)", tc.source, address, subdir, tc.address, tc.subdir)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"slices"
 	"strings"
 
+	version "github.com/hashicorp/go-version"
 	"github.com/minamijoyo/tfupdate/tfregistry"
 )
 
@@ -43,12 +44,70 @@ type Option struct {
 
 	// tfregistryConfig is a configuration for Terraform Registry API.
 	tfregistryConfig tfregistry.Config
+
+	// If a noFormat flag is true, updated files are not formatted and only the
+	// changed tokens are rewritten.
+	noFormat bool
+
+	// bump is a level of version bump. See VersionPolicy for details.
+	bump string
+
+	// releases is a list of available versions in semver order for bump.
+	releases []*version.Version
+
+	// If a preserveOperators flag is true, only version operands in the
+	// current version constraint are rewritten.
+	preserveOperators bool
+
+	// If an allowDowngrade flag is true, updating to a lower version than the
+	// current one is allowed.
+	allowDowngrade bool
+
+	// If a prune flag is true, provider blocks which are no longer required
+	// are removed from dependency lock files.
+	prune bool
+
+	// If a createLockFile flag is true, dependency lock files are created in
+	// modules which have pinned providers but no lock file yet.
+	createLockFile bool
+
+	// lockCacheDir is a directory to persist hash values of provider packages
+	// for dependency lock files. If empty, the persistent cache is disabled.
+	lockCacheDir string
+
+	// lockPluginDirs is a list of local directories to look up unpacked
+	// provider packages before downloading them.
+	lockPluginDirs []string
+
+	// lockParallelism is the maximum number of provider packages downloaded
+	// concurrently for dependency lock files.
+	lockParallelism int
+
+	// jobs is the number of modules processed concurrently. See WalkPolicy for
+	// details.
+	jobs int
+
+	// If a keepGoing flag is true, errors for files are recorded and the rest
+	// of files are still updated.
+	keepGoing bool
+}
+
+// UpdatePolicy is a set of parameters to match and rewrite dependencies.
+type UpdatePolicy struct {
+	// SourceMatchType defines how to match a module name to the module source.
+	// Valid values are "full" or "regex". It is used only for the module
+	// updater.
+	SourceMatchType string
+
+	// NoFormat is a flag to rewrite only the changed tokens without formatting
+	// the whole file.
+	NoFormat bool
 }
 
 // NewOption returns an option.
-func NewOption(updateType string, name string, version string, platforms []string, recursive bool, ignorePaths []string, sourceMatchType string, tfregistryConfig tfregistry.Config) (Option, error) {
-	regexps := make([]*regexp.Regexp, 0, len(ignorePaths))
-	for _, ignorePath := range ignorePaths {
+func NewOption(updateType string, name string, version string, platforms []string, tfregistryConfig tfregistry.Config, updatePolicy UpdatePolicy, versionPolicy VersionPolicy, lockPolicy LockPolicy, walkPolicy WalkPolicy) (Option, error) {
+	regexps := make([]*regexp.Regexp, 0, len(walkPolicy.IgnorePaths))
+	for _, ignorePath := range walkPolicy.IgnorePaths {
 		if len(ignorePath) == 0 {
 			continue
 		}
@@ -60,21 +119,42 @@ func NewOption(updateType string, name string, version string, platforms []strin
 		regexps = append(regexps, r)
 	}
 
-	nameRegex, err := nameRegex(updateType, name, sourceMatchType)
+	nameRegex, err := nameRegex(updateType, name, updatePolicy.SourceMatchType)
 	if err != nil {
 		return Option{}, err
 	}
 
-	return Option{
-		updateType:       updateType,
-		name:             name,
-		version:          version,
-		platforms:        platforms,
-		recursive:        recursive,
-		ignorePaths:      regexps,
-		nameRegex:        nameRegex,
-		tfregistryConfig: tfregistryConfig,
-	}, nil
+	if !slices.Contains(validBumps, versionPolicy.Bump) {
+		return Option{}, fmt.Errorf("invalid bump: %s. Valid values are \"patch\", \"minor\" or \"major\"", versionPolicy.Bump)
+	}
+
+	o := Option{
+		updateType:        updateType,
+		name:              name,
+		version:           version,
+		platforms:         platforms,
+		recursive:         walkPolicy.Recursive,
+		ignorePaths:       regexps,
+		nameRegex:         nameRegex,
+		tfregistryConfig:  tfregistryConfig,
+		noFormat:          updatePolicy.NoFormat,
+		bump:              versionPolicy.Bump,
+		preserveOperators: versionPolicy.PreserveOperators,
+		allowDowngrade:    versionPolicy.AllowDowngrade,
+		prune:             lockPolicy.Prune,
+		createLockFile:    lockPolicy.Create,
+		lockCacheDir:      lockPolicy.CacheDir,
+		lockPluginDirs:    lockPolicy.PluginDirs,
+		lockParallelism:   lockPolicy.Parallelism,
+		jobs:              walkPolicy.Jobs,
+		keepGoing:         walkPolicy.KeepGoing,
+	}
+
+	if o.bump != "" {
+		o.releases = parseReleases(versionPolicy.Releases)
+	}
+
+	return o, nil
 }
 
 func nameRegex(updateType string, name string, sourceMatchType string) (*regexp.Regexp, error) {
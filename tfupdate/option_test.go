@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"testing"
 
+	version "github.com/hashicorp/go-version"
 	"github.com/minamijoyo/tfupdate/tfregistry"
 )
 
@@ -18,6 +19,10 @@ func TestNewOption(t *testing.T) {
 		ignorePaths      []string
 		sourceMatchType  string
 		tfregistryConfig tfregistry.Config
+		noFormat         bool
+		versionPolicy    VersionPolicy
+		lockPolicy       LockPolicy
+		walkPolicy       WalkPolicy
 		want             Option
 		ok               bool
 	}{
@@ -110,6 +115,27 @@ func TestNewOption(t *testing.T) {
 			want:             Option{},
 			ok:               false,
 		},
+		{
+			updateType:       "terraform",
+			version:          "0.12.7",
+			platforms:        []string{},
+			recursive:        true,
+			ignorePaths:      []string{},
+			sourceMatchType:  "full",
+			tfregistryConfig: tfregistry.Config{},
+			noFormat:         true,
+			want: Option{
+				updateType:       "terraform",
+				version:          "0.12.7",
+				platforms:        []string{},
+				recursive:        true,
+				ignorePaths:      []*regexp.Regexp{},
+				nameRegex:        nil,
+				tfregistryConfig: tfregistry.Config{},
+				noFormat:         true,
+			},
+			ok: true,
+		},
 		{
 			updateType:       "lock",
 			version:          "",
@@ -129,6 +155,35 @@ func TestNewOption(t *testing.T) {
 			},
 			ok: true,
 		},
+		{
+			updateType:       "lock",
+			version:          "",
+			platforms:        []string{"linux_amd64"},
+			recursive:        true,
+			ignorePaths:      []string{},
+			sourceMatchType:  "full",
+			tfregistryConfig: tfregistry.Config{},
+			lockPolicy: LockPolicy{
+				Prune:       true,
+				CacheDir:    "/tmp/tfupdate",
+				PluginDirs:  []string{"/tmp/plugin-cache"},
+				Parallelism: 8,
+			},
+			want: Option{
+				updateType:       "lock",
+				version:          "",
+				platforms:        []string{"linux_amd64"},
+				recursive:        true,
+				ignorePaths:      []*regexp.Regexp{},
+				nameRegex:        nil,
+				tfregistryConfig: tfregistry.Config{},
+				prune:            true,
+				lockCacheDir:     "/tmp/tfupdate",
+				lockPluginDirs:   []string{"/tmp/plugin-cache"},
+				lockParallelism:  8,
+			},
+			ok: true,
+		},
 		{
 			updateType:       "module",
 			name:             "terraform-aws-modules/vpc/aws",
@@ -204,10 +259,62 @@ func TestNewOption(t *testing.T) {
 			tfregistryConfig: tfregistry.Config{},
 			ok:               false,
 		},
+		{
+			updateType:       "provider",
+			name:             "aws",
+			version:          "5.1.0",
+			platforms:        []string{},
+			recursive:        true,
+			ignorePaths:      []string{},
+			sourceMatchType:  "full",
+			tfregistryConfig: tfregistry.Config{},
+			versionPolicy: VersionPolicy{
+				Bump:     "minor",
+				Releases: []string{"5.1.0", "4.67.0", "5.0.0-beta1", "foo", "5.0.0"},
+			},
+			want: Option{
+				updateType:       "provider",
+				name:             "aws",
+				version:          "5.1.0",
+				platforms:        []string{},
+				recursive:        true,
+				ignorePaths:      []*regexp.Regexp{},
+				nameRegex:        nil,
+				tfregistryConfig: tfregistry.Config{},
+				bump:             "minor",
+				releases: []*version.Version{
+					version.Must(version.NewVersion("4.67.0")),
+					version.Must(version.NewVersion("5.0.0")),
+					version.Must(version.NewVersion("5.1.0")),
+				},
+			},
+			ok: true,
+		},
+		{
+			updateType:       "provider",
+			name:             "aws",
+			version:          "5.1.0",
+			platforms:        []string{},
+			recursive:        true,
+			ignorePaths:      []string{},
+			sourceMatchType:  "full",
+			tfregistryConfig: tfregistry.Config{},
+			versionPolicy: VersionPolicy{
+				Bump: "invalid",
+			},
+			ok: false,
+		},
 	}
 
 	for _, tc := range cases {
-		got, err := NewOption(tc.updateType, tc.name, tc.version, tc.platforms, tc.recursive, tc.ignorePaths, tc.sourceMatchType, tc.tfregistryConfig)
+		updatePolicy := UpdatePolicy{
+			SourceMatchType: tc.sourceMatchType,
+			NoFormat:        tc.noFormat,
+		}
+		walkPolicy := tc.walkPolicy
+		walkPolicy.Recursive = tc.recursive
+		walkPolicy.IgnorePaths = tc.ignorePaths
+		got, err := NewOption(tc.updateType, tc.name, tc.version, tc.platforms, tc.tfregistryConfig, updatePolicy, tc.versionPolicy, tc.lockPolicy, walkPolicy)
 		if tc.ok && err != nil {
 			t.Errorf("NewOption() with updateType = %s, name = %s, version = %s, platforms = %#v, recursive = %t, ignorePath = %#v returns unexpected err: %+v", tc.updateType, tc.name, tc.version, tc.platforms, tc.recursive, tc.ignorePaths, err)
 		}
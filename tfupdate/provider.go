@@ -3,14 +3,16 @@ package tfupdate
 import (
 	"context"
 	"fmt"
+	"maps"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/pkg/errors"
-	"github.com/zclconf/go-cty/cty"
+	"github.com/spf13/afero"
 )
 
 // ProviderUpdater is a updater implementation which updates the provider version constraint.
@@ -43,14 +45,14 @@ func (u *ProviderUpdater) Update(_ context.Context, mc *ModuleContext, filename
 		return nil
 	}
 
-	if err := u.updateTerraformBlock(mc, f); err != nil {
+	if err := u.updateTerraformBlock(mc, filename, f); err != nil {
 		return err
 	}
 
-	return u.updateProviderBlock(f)
+	return u.updateProviderBlock(mc, filename, f)
 }
 
-func (u *ProviderUpdater) updateTerraformBlock(mc *ModuleContext, f *hclwrite.File) error {
+func (u *ProviderUpdater) updateTerraformBlock(mc *ModuleContext, filename string, f *hclwrite.File) error {
 	for _, tf := range allMatchingBlocks(f.Body(), "terraform", []string{}) {
 		p := tf.Body().FirstMatchingBlock("required_providers", []string{})
 		if p == nil {
@@ -79,10 +81,10 @@ func (u *ProviderUpdater) updateTerraformBlock(mc *ModuleContext, f *hclwrite.Fi
 			// If the expression can be parsed as a static expression and its type is a primitive,
 			// then it's a legacy string syntax.
 			if expr, err := hclAttr.Expr.Value(nil); err == nil && expr.Type().IsPrimitiveType() {
-				u.updateTerraformRequiredProvidersBlockAsString(p)
+				u.updateTerraformRequiredProvidersBlockAsString(mc, filename, p, name)
 			} else {
 				// Otherwise, it's an object syntax.
-				if err := u.updateTerraformRequiredProvidersBlockAsObject(p, name, hclAttr); err != nil {
+				if err := u.updateTerraformRequiredProvidersBlockAsObject(mc, filename, p, name, hclAttr); err != nil {
 					return err
 				}
 			}
@@ -92,7 +94,7 @@ func (u *ProviderUpdater) updateTerraformBlock(mc *ModuleContext, f *hclwrite.Fi
 	return nil
 }
 
-func (u *ProviderUpdater) updateTerraformRequiredProvidersBlockAsObject(p *hclwrite.Block, name string, hclAttr *hcl.Attribute) error {
+func (u *ProviderUpdater) updateTerraformRequiredProvidersBlockAsObject(mc *ModuleContext, filename string, p *hclwrite.Block, name string, hclAttr *hcl.Attribute) error {
 	// terraform {
 	//   required_providers {
 	//     aws = {
@@ -157,7 +159,9 @@ func (u *ProviderUpdater) updateTerraformRequiredProvidersBlockAsObject(p *hclwr
 	// Since I've checked for the existence of the version key in advance,
 	// if we reach here, we found the token to be updated.
 	// So we now update bytes of the token in place.
-	tokens[i].Bytes = []byte(u.version)
+	newVersion := mc.resolveVersion(filename, oldVersion, u.version)
+	tokens[i].Bytes = []byte(newVersion)
+	mc.recordChange(newChange(filename, "required_providers", nil, name+".version", oldVersion, newVersion))
 
 	return nil
 }
@@ -166,6 +170,13 @@ func (u *ProviderUpdater) updateTerraformRequiredProvidersBlockAsObject(p *hclwr
 // the "version" key.
 // If the version key is missing, just returns an empty string without an error.
 func detectVersionInObject(hclAttr *hcl.Attribute) (string, error) {
+	return detectStringInObject(hclAttr, "version")
+}
+
+// detectStringInObject parses an object expression and detects a string value
+// for a given key.
+// If the key is missing, just returns an empty string without an error.
+func detectStringInObject(hclAttr *hcl.Attribute, name string) (string, error) {
 	// The configuration_aliases syntax isn't directly related version updating,
 	// but it contains provider references and causes a parse error without an EvalContext.
 	// So we treat the expression as a hcl.ExprMap to avoid fully decoding the object.
@@ -174,40 +185,162 @@ func detectVersionInObject(hclAttr *hcl.Attribute) (string, error) {
 		return "", fmt.Errorf("failed to parse expr as hcl.ExprMap: %s", diags)
 	}
 
-	oldVersion := ""
+	ret := ""
 	for _, kv := range kvs {
 		key, diags := kv.Key.Value(nil)
 		if diags.HasErrors() {
 			return "", fmt.Errorf("failed to get key: %s", diags)
 		}
-		if key.AsString() == "version" {
+		if key.AsString() == name {
 			value, diags := kv.Value.Value(nil)
 			if diags.HasErrors() {
 				return "", fmt.Errorf("failed to get value: %s", diags)
 			}
-			oldVersion = value.AsString()
+			ret = value.AsString()
 		}
 	}
 
-	return oldVersion, nil
+	return ret, nil
 }
 
-func (u *ProviderUpdater) updateTerraformRequiredProvidersBlockAsString(p *hclwrite.Block) {
+func (u *ProviderUpdater) updateTerraformRequiredProvidersBlockAsString(mc *ModuleContext, filename string, p *hclwrite.Block, name string) {
 	// terraform {
 	//   required_providers {
 	//     aws = "2.65.0"
 	//   }
 	// }
-	p.Body().SetAttributeValue(u.name, cty.StringVal(u.version))
+	oldVersion := getAttributeValueAsUnquotedString(p.Body().GetAttribute(name))
+	newVersion := mc.resolveVersion(filename, oldVersion, u.version)
+	setAttributeValueAsString(p.Body(), name, newVersion)
+	mc.recordChange(newChange(filename, "required_providers", nil, name, oldVersion, newVersion))
 }
 
-func (u *ProviderUpdater) updateProviderBlock(f *hclwrite.File) error {
+func (u *ProviderUpdater) updateProviderBlock(mc *ModuleContext, filename string, f *hclwrite.File) error {
 	for _, p := range allMatchingBlocks(f.Body(), "provider", []string{u.name}) {
 		// set a version to attribute value only if the key exists
-		if p.Body().GetAttribute("version") != nil {
-			p.Body().SetAttributeValue("version", cty.StringVal(u.version))
+		if attr := p.Body().GetAttribute("version"); attr != nil {
+			oldVersion := getAttributeValueAsUnquotedString(attr)
+			newVersion := mc.resolveVersion(filename, oldVersion, u.version)
+			setAttributeValueAsString(p.Body(), "version", newVersion)
+			mc.recordChange(newChange(filename, "provider", p.Labels(), "version", oldVersion, newVersion))
 		}
 	}
 
 	return nil
 }
+
+// updateJSON updates the provider version constraint in the JSON syntax.
+func (u *ProviderUpdater) updateJSON(_ context.Context, mc *ModuleContext, filename string, f *jsonFile) error {
+	name := u.name
+	// If the name contains /, assume that a namespace is intended and check the source.
+	if strings.Contains(u.name, "/") {
+		name = mc.ResolveProviderShortNameFromSource(u.name)
+	}
+
+	if name != "" {
+		for _, tf := range allMatchingJSONBlocks(f.body, "terraform") {
+			for _, p := range allMatchingJSONBlocks(tf, "required_providers") {
+				u.updateRequiredProviderJSON(mc, filename, f, p, name)
+			}
+		}
+	}
+
+	for _, p := range allMatchingJSONLabeledBlocks(f.body, "provider") {
+		if p.label != u.name {
+			continue
+		}
+		// set a version to attribute value only if the key exists
+		if attr := f.getString(p.body, "version"); attr != nil {
+			oldVersion := attr.str
+			newVersion := mc.resolveVersion(filename, oldVersion, u.version)
+			f.setString(attr, newVersion)
+			mc.recordChange(newChange(filename, "provider", []string{p.label}, "version", oldVersion, newVersion))
+		}
+	}
+
+	return nil
+}
+
+// updateRequiredProviderJSON updates a version constraint for a given local
+// name in the required_providers block in the JSON syntax.
+func (u *ProviderUpdater) updateRequiredProviderJSON(mc *ModuleContext, filename string, f *jsonFile, p hcl.Body, name string) {
+	a := getJSONAttribute(p, name)
+	if a == nil {
+		return
+	}
+
+	if v := f.stringValue(a.Expr); v != nil {
+		// "aws": "2.65.0"
+		oldVersion := v.str
+		newVersion := mc.resolveVersion(filename, oldVersion, u.version)
+		f.setString(v, newVersion)
+		mc.recordChange(newChange(filename, "required_providers", nil, name, oldVersion, newVersion))
+	} else {
+		// "aws": { "source": "hashicorp/aws", "version": "2.65.0" }
+		// If the version key is missing, just ignore it.
+		if attr := f.getMemberString(a.Expr, "version"); attr != nil {
+			oldVersion := attr.str
+			newVersion := mc.resolveVersion(filename, oldVersion, u.version)
+			f.setString(attr, newVersion)
+			mc.recordChange(newChange(filename, "required_providers", nil, name+".version", oldVersion, newVersion))
+		}
+	}
+}
+
+// ResolveProviderSource returns a source address of the provider specified by
+// the name in the option. If the name is a short name such as aws, it scans
+// the required_providers blocks in a given file or directory to find the
+// actual source address for the local name. If the local name is not found,
+// it complements the official hashicorp namespace as Terraform does. If the
+// local name maps to different source addresses in different modules, it
+// returns an error because we cannot decide which one to use.
+func ResolveProviderSource(fs afero.Fs, o Option, path string) (string, error) {
+	if strings.Contains(o.name, "/") {
+		// The name is already a source address.
+		return o.name, nil
+	}
+
+	// We only inspect modules here, so an updater is not needed.
+	gc := &GlobalContext{
+		fs:     fs,
+		option: o,
+	}
+
+	// A map of a normalized source address to directories where it is found.
+	found := make(map[string][]string)
+	err := walkFileOrDir(gc, path, func(mc *ModuleContext) error {
+		p, ok := mc.requiredProviders[o.name]
+		if !ok || p.Source == "" {
+			return nil
+		}
+		source := normalizeProviderSource(p.Source)
+		found[source] = append(found[source], mc.dir)
+		return nil
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(found) {
+	case 0:
+		return "hashicorp/" + o.name, nil
+	case 1:
+		for source := range found {
+			return source, nil
+		}
+	}
+
+	conflicts := []string{}
+	for _, source := range slices.Sorted(maps.Keys(found)) {
+		conflicts = append(conflicts, fmt.Sprintf("%s (%s)", source, strings.Join(found[source], ", ")))
+	}
+	return "", fmt.Errorf("the provider %s maps to different source addresses: %s", o.name, strings.Join(conflicts, ", "))
+}
+
+// normalizeProviderSource returns a normalized form of the source address for
+// comparison. Source addresses are case-insensitive and the default hostname
+// for Terraform Registry can be omitted.
+func normalizeProviderSource(source string) string {
+	s := strings.ToLower(source)
+	return strings.TrimPrefix(s, "registry.terraform.io/")
+}
@@ -542,3 +542,202 @@ provider "registry.terraform.io/hashicorp/null" {
 		}
 	}
 }
+
+func TestResolveProviderSource(t *testing.T) {
+	cases := []struct {
+		desc      string
+		files     map[string]string
+		name      string
+		path      string
+		recursive bool
+		want      string
+		ok        bool
+	}{
+		{
+			desc: "source address",
+			files: map[string]string{
+				"a/main.tf": `
+terraform {
+  required_providers {
+    github = {
+      source = "integrations/github"
+    }
+  }
+}
+`,
+			},
+			name: "hashicorp/github",
+			path: "a",
+			want: "hashicorp/github",
+			ok:   true,
+		},
+		{
+			desc: "resolve short name",
+			files: map[string]string{
+				"a/main.tf": `
+terraform {
+  required_providers {
+    github = {
+      source = "integrations/github"
+    }
+  }
+}
+`,
+			},
+			name: "github",
+			path: "a",
+			want: "integrations/github",
+			ok:   true,
+		},
+		{
+			desc: "resolve short name for file",
+			files: map[string]string{
+				"a/main.tf": `
+terraform {
+  required_providers {
+    github = {
+      source = "integrations/github"
+    }
+  }
+}
+`,
+			},
+			name: "github",
+			path: "a/main.tf",
+			want: "integrations/github",
+			ok:   true,
+		},
+		{
+			desc: "not found",
+			files: map[string]string{
+				"a/main.tf": `
+terraform {
+  required_providers {
+    aws = {
+      source = "hashicorp/aws"
+    }
+  }
+}
+`,
+			},
+			name: "github",
+			path: "a",
+			want: "hashicorp/github",
+			ok:   true,
+		},
+		{
+			desc: "same source in different notations",
+			files: map[string]string{
+				"a/main.tf": `
+terraform {
+  required_providers {
+    github = {
+      source = "integrations/github"
+    }
+  }
+}
+`,
+				"a/b/main.tf": `
+terraform {
+  required_providers {
+    github = {
+      source = "registry.terraform.io/Integrations/github"
+    }
+  }
+}
+`,
+			},
+			name:      "github",
+			path:      "a",
+			recursive: true,
+			want:      "integrations/github",
+			ok:        true,
+		},
+		{
+			desc: "conflict",
+			files: map[string]string{
+				"a/main.tf": `
+terraform {
+  required_providers {
+    github = {
+      source = "integrations/github"
+    }
+  }
+}
+`,
+				"a/b/main.tf": `
+terraform {
+  required_providers {
+    github = {
+      source = "hashicorp/github"
+    }
+  }
+}
+`,
+			},
+			name:      "github",
+			path:      "a",
+			recursive: true,
+			want:      "",
+			ok:        false,
+		},
+		{
+			desc: "not recursive",
+			files: map[string]string{
+				"a/main.tf": `
+terraform {
+  required_providers {
+    github = {
+      source = "integrations/github"
+    }
+  }
+}
+`,
+				"a/b/main.tf": `
+terraform {
+  required_providers {
+    github = {
+      source = "hashicorp/github"
+    }
+  }
+}
+`,
+			},
+			name:      "github",
+			path:      "a",
+			recursive: false,
+			want:      "integrations/github",
+			ok:        true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for filename, src := range tc.files {
+				if err := fs.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := afero.WriteFile(fs, filename, []byte(src), 0644); err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			o := Option{
+				updateType: "provider",
+				name:       tc.name,
+				recursive:  tc.recursive,
+			}
+			got, err := ResolveProviderSource(fs, o, tc.path)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expects to return an error, but no error. got = %s", got)
+			}
+			if got != tc.want {
+				t.Errorf("got = %s, but want = %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package tfupdate
+
+// Result is a result of updating a single file.
+type Result struct {
+	// Filename is a path of the updated file.
+	Filename string
+
+	// Original is the contents of the file before updating.
+	Original []byte
+
+	// Updated is the contents of the file after updating.
+	Updated []byte
+
+	// Changes is a list of attributes rewritten in the file.
+	Changes []Change
+}
+
+// Change is a record of an attribute value rewritten by an updater.
+type Change struct {
+	// Filename is a path of the file.
+	Filename string `json:"filename"`
+
+	// BlockType is a type of the block containing the attribute, such as
+	// terraform, required_providers, provider or module.
+	BlockType string `json:"block_type"`
+
+	// BlockLabels is a list of labels of the block.
+	BlockLabels []string `json:"block_labels"`
+
+	// Attribute is a name of the attribute.
+	// For an object syntax in required_providers, the key in the object is
+	// joined with a dot, such as aws.version.
+	// For version files such as .terraform-version, the block type is empty
+	// and the attribute is a name of the tool, such as terraform.
+	Attribute string `json:"attribute"`
+
+	// OldValue is an unquoted value of the attribute before updating.
+	// If the attribute didn't exist, it is an empty string.
+	OldValue string `json:"old_value"`
+
+	// NewValue is an unquoted value of the attribute after updating.
+	NewValue string `json:"new_value"`
+}
+
+// newChange returns a new Change instance.
+func newChange(filename string, blockType string, blockLabels []string, attribute string, oldValue string, newValue string) Change {
+	labels := []string{}
+	labels = append(labels, blockLabels...)
+	return Change{
+		Filename:    filename,
+		BlockType:   blockType,
+		BlockLabels: labels,
+		Attribute:   attribute,
+		OldValue:    oldValue,
+		NewValue:    newValue,
+	}
+}
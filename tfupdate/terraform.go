@@ -6,7 +6,6 @@ import (
 
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/pkg/errors"
-	"github.com/zclconf/go-cty/cty"
 )
 
 // TerraformUpdater is a updater implementation which updates the terraform version constraint.
@@ -27,7 +26,7 @@ func NewTerraformUpdater(version string) (Updater, error) {
 
 // Update updates the terraform version constraint.
 // Note that this method will rewrite the AST passed as an argument.
-func (u *TerraformUpdater) Update(_ context.Context, _ *ModuleContext, filename string, f *hclwrite.File) error {
+func (u *TerraformUpdater) Update(_ context.Context, mc *ModuleContext, filename string, f *hclwrite.File) error {
 	if filepath.Base(filename) == ".terraform.lock.hcl" {
 		// skip a lock file.
 		return nil
@@ -35,10 +34,34 @@ func (u *TerraformUpdater) Update(_ context.Context, _ *ModuleContext, filename
 
 	for _, tf := range allMatchingBlocks(f.Body(), "terraform", []string{}) {
 		// set a version to attribute value only if the key exists
-		if tf.Body().GetAttribute("required_version") != nil {
-			tf.Body().SetAttributeValue("required_version", cty.StringVal(u.version))
+		if attr := tf.Body().GetAttribute("required_version"); attr != nil {
+			oldVersion := getAttributeValueAsUnquotedString(attr)
+			newVersion := mc.resolveVersion(filename, oldVersion, u.version)
+			setAttributeValueAsString(tf.Body(), "required_version", newVersion)
+			mc.recordChange(newChange(filename, "terraform", nil, "required_version", oldVersion, newVersion))
 		}
 	}
 
 	return nil
 }
+
+// updateJSON updates the terraform version constraint in the JSON syntax.
+func (u *TerraformUpdater) updateJSON(_ context.Context, mc *ModuleContext, filename string, f *jsonFile) error {
+	for _, tf := range allMatchingJSONBlocks(f.body, "terraform") {
+		// set a version to attribute value only if the key exists
+		if attr := f.getString(tf, "required_version"); attr != nil {
+			oldVersion := attr.str
+			newVersion := mc.resolveVersion(filename, oldVersion, u.version)
+			f.setString(attr, newVersion)
+			mc.recordChange(newChange(filename, "terraform", nil, "required_version", oldVersion, newVersion))
+		}
+	}
+
+	return nil
+}
+
+// updateVersionFile updates the terraform version in .terraform-version or
+// .tool-versions.
+func (u *TerraformUpdater) updateVersionFile(mc *ModuleContext, filename string, src []byte) []byte {
+	return updateToolVersion(mc, filename, src, "terraform", ".terraform-version", u.version)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/afero"
 )
 
 func TestNewTerraformUpdater(t *testing.T) {
@@ -142,7 +143,17 @@ terraform {
 			t.Fatalf("unexpected diagnostics: %s", diags)
 		}
 
-		err := u.Update(context.Background(), nil, tc.filename, f)
+		gc, err := NewGlobalContext(afero.NewMemMapFs(), Option{updateType: "terraform", version: tc.version})
+		if err != nil {
+			t.Fatalf("failed to new global context: %s", err)
+		}
+
+		mc, err := NewModuleContext(".", gc)
+		if err != nil {
+			t.Fatalf("failed to new module context: %s", err)
+		}
+
+		err = u.Update(context.Background(), mc, tc.filename, f)
 		if tc.ok && err != nil {
 			t.Errorf("Update() with src = %s, version = %s returns unexpected err: %+v", tc.src, tc.version, err)
 		}
@@ -32,7 +32,7 @@ func NewUpdater(o Option) (Updater, error) {
 	case "module":
 		return NewModuleUpdater(o.name, o.version, o.nameRegex)
 	case "lock":
-		return NewLockUpdater(o.platforms, o.tfregistryConfig)
+		return NewLockUpdater(o.platforms, o.tfregistryConfig, o.lockCacheDir, o.lockPluginDirs, o.lockParallelism)
 	default:
 		return nil, errors.Errorf("failed to new updater. unknown type: %s", o.updateType)
 	}
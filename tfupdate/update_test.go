@@ -8,7 +8,9 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	version "github.com/hashicorp/go-version"
 	"github.com/minamijoyo/tfupdate/lock"
+	"github.com/minamijoyo/tfupdate/tfregistry"
 	"github.com/spf13/afero"
 )
 
@@ -68,7 +70,8 @@ func TestNewUpdater(t *testing.T) {
 				platforms:  []string{"darwin_arm64", "darwin_amd64", "linux_amd64"},
 			},
 			want: &LockUpdater{
-				platforms: []string{"darwin_arm64", "darwin_amd64", "linux_amd64"},
+				platforms:         []string{"darwin_arm64", "darwin_amd64", "linux_amd64"},
+				availableVersions: map[string]version.Collection{},
 			},
 			ok: true,
 		},
@@ -99,6 +102,8 @@ func TestNewUpdater(t *testing.T) {
 			cmp.AllowUnexported(ModuleUpdater{}),
 			cmp.AllowUnexported(LockUpdater{}),
 			cmpopts.IgnoreInterfaces(struct{ lock.Index }{}),
+			cmpopts.IgnoreInterfaces(struct{ tfregistry.ProviderV1API }{}),
+			cmpopts.IgnoreFields(LockUpdater{}, "mu"),
 		}
 		if diff := cmp.Diff(got, tc.want, opts...); diff != "" {
 			t.Errorf("got: %s, want = %s, diff = %s", spew.Sdump(got), spew.Sdump(tc.want), diff)
@@ -124,14 +129,11 @@ terraform {
 				updateType: "terraform",
 				version:    "0.12.7",
 			},
-			// Note the lack of space here.
-			// the current implementation of (*hclwrite.Body).SetAttributeValue()
-			// does not seem to preserve an original SpaceBefore value of attribute.
-			// This is a bug of upstream.
-			// We avoid this by formatting the output of this function.
+			// Only the changed tokens are rewritten, so the original spacing is
+			// preserved without formatting.
 			want: `
 terraform {
-  required_version ="0.12.7"
+  required_version = "0.12.7"
 }
 `,
 			isUpdated: true,
@@ -149,7 +151,7 @@ terraform {
 			},
 			want: `
 terraform {
-  required_version ="1.9.0"
+  required_version = "1.9.0"
 }
 `,
 			isUpdated: true,
@@ -168,7 +170,7 @@ provider "aws" {
 			},
 			want: `
 provider "aws" {
-  version ="2.23.0"
+  version = "2.23.0"
 }
 `,
 			isUpdated: true,
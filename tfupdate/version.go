@@ -0,0 +1,418 @@
+package tfupdate
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// VersionPolicy is a set of parameters to decide a new version from the
+// current one found in each file.
+type VersionPolicy struct {
+	// Bump is a level of version bump. Valid values are as follows:
+	// - "" (empty): Set the given version as is.
+	// - patch: Update to the newest release within the current minor version.
+	// - minor: Update to the newest release within the current major version.
+	// - major: Update to the newest release.
+	Bump string
+
+	// Releases is a list of available versions to select from.
+	// It is only used when Bump is set.
+	Releases []string
+
+	// PreserveOperators is a flag to rewrite only version operands in the
+	// current version constraint and keep its operators such as ~> and >=.
+	PreserveOperators bool
+
+	// AllowDowngrade is a flag to allow updating to a version lower than the
+	// current one. By default, such updates are skipped with a warning.
+	AllowDowngrade bool
+}
+
+// validBumps is a list of valid values for VersionPolicy.Bump.
+var validBumps = []string{"", "patch", "minor", "major"}
+
+// parseReleases parses a list of versions and returns them in semver order.
+// Versions which cannot be parsed or pre-releases are ignored.
+func parseReleases(releases []string) []*version.Version {
+	var versions []*version.Version
+	for _, r := range releases {
+		v, err := version.NewVersion(r)
+		if err != nil {
+			log.Printf("[DEBUG] parseReleases: ignore version parse error: %s", err)
+			continue
+		}
+		if v.Prerelease() != "" {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	sort.Sort(version.Collection(versions))
+	return versions
+}
+
+// constraintRegexp is a regular expression for a single version constraint
+// such as ">= 1.5". It keeps whitespaces to rewrite only the version operand.
+var constraintRegexp = regexp.MustCompile(`^(\s*)(=|!=|>=|<=|>|<|~>)?(\s*)(\S+)(\s*)$`)
+
+// resolveVersion returns a new version to replace the current one according to
+// the version policy. The target is the version given to the updater.
+// If preserveOperators is set, the current version constraint is rewritten
+// with the new version while keeping its operators. Otherwise the current
+// value is entirely replaced with the new version.
+func (o *Option) resolveVersion(current string, target string) string {
+	if !o.preserveOperators {
+		return o.bumpVersion(current, target)
+	}
+
+	if _, err := version.NewConstraint(current); err != nil {
+		log.Printf("[DEBUG] resolveVersion: ignore constraint parse error: current = %s, err = %s", current, err)
+		return o.bumpVersion(current, target)
+	}
+
+	newVersion := target
+	if o.bump != "" {
+		// Bump from the lower bound of the current constraint.
+		base := lowerBound(current)
+		if base == "" {
+			return current
+		}
+		newVersion = o.bumpVersion(base, target)
+		if newVersion == base {
+			return current
+		}
+	}
+
+	v, err := version.NewVersion(newVersion)
+	if err != nil {
+		// The target is a version constraint, not an exact version.
+		// Respect the user's intent and set it as is.
+		return target
+	}
+
+	return rewriteConstraint(current, v)
+}
+
+// bumpVersion returns a new version to replace the current exact version.
+// If no bump is set, the target is returned as is.
+// If the current version is not an exact version, we cannot decide how far to
+// bump it, so it returns the current one as is.
+func (o *Option) bumpVersion(current string, target string) string {
+	if o.bump == "" {
+		return target
+	}
+
+	c, err := version.NewVersion(current)
+	if err != nil {
+		log.Printf("[DEBUG] bumpVersion: ignore version parse error: current = %s, err = %s", current, err)
+		return current
+	}
+
+	var selected *version.Version
+	for _, r := range o.releases {
+		if !withinBump(o.bump, c, r) {
+			continue
+		}
+		// The releases are sorted, so the last one wins.
+		selected = r
+	}
+
+	// Bumping never downgrades the current version.
+	if selected == nil || !selected.GreaterThan(c) {
+		return current
+	}
+
+	return selected.String()
+}
+
+// lowerBound returns a version operand of the first lower bound in the
+// version constraints. If not found, it returns an empty string.
+func lowerBound(constraints string) string {
+	for _, c := range strings.Split(constraints, ",") {
+		m := constraintRegexp.FindStringSubmatch(c)
+		if m == nil {
+			continue
+		}
+		switch m[2] {
+		case "", "=", ">=", "~>":
+			return m[4]
+		}
+	}
+	return ""
+}
+
+// rewriteConstraint rewrites version operands in the version constraints with
+// a new version while keeping operators and the precision of each operand.
+//   - A lower bound (=, >=, ~> or no operator) is set to the new version.
+//   - An upper bound (< or <=) is raised only if it excludes the new version.
+//     For <, it moves to the next boundary at the same level, that is, < 2.0
+//     becomes < 3.0 for 2.3.0.
+//   - Others (!= and >) are kept as is.
+//
+// The constraints are assumed to be valid.
+func rewriteConstraint(constraints string, v *version.Version) string {
+	parts := strings.Split(constraints, ",")
+	for i, c := range parts {
+		m := constraintRegexp.FindStringSubmatch(c)
+		if m == nil {
+			continue
+		}
+		operator, operand := m[2], m[4]
+		b, err := version.NewVersion(operand)
+		if err != nil {
+			continue
+		}
+		precision := versionPrecision(operand)
+
+		switch operator {
+		case "", "=", ">=", "~>":
+			operand = formatVersion(v.Segments(), v, precision)
+		case "<":
+			if v.LessThan(b) {
+				continue
+			}
+			operand = formatVersion(nextBoundary(b.Segments(), v.Segments(), precision), nil, precision)
+		case "<=":
+			if !v.GreaterThan(b) {
+				continue
+			}
+			operand = formatVersion(v.Segments(), v, precision)
+		default:
+			continue
+		}
+
+		parts[i] = m[1] + operator + m[3] + operand + m[5]
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// versionPrecision returns the number of segments of a version string.
+// For example, it returns 2 for 1.5 and 3 for 1.5.0-beta1.
+func versionPrecision(s string) int {
+	core, _, _ := strings.Cut(s, "-")
+	core, _, _ = strings.Cut(core, "+")
+	return strings.Count(core, ".") + 1
+}
+
+// nextBoundary returns segments for the next boundary of the upper bound b
+// which includes the version v. The boundary level is determined by the last
+// non-zero segment of b within the given precision.
+func nextBoundary(b []int, v []int, precision int) []int {
+	level := 0
+	for i := 0; i < precision && i < len(b); i++ {
+		if b[i] != 0 {
+			level = i
+		}
+	}
+
+	next := make([]int, precision)
+	copy(next, v[:min(level+1, len(v))])
+	next[level]++
+	return next
+}
+
+// formatVersion formats version segments with the given precision.
+// If v is a pre-release, its precision cannot be reduced, so the full version
+// string is returned.
+func formatVersion(segments []int, v *version.Version, precision int) string {
+	if v != nil && (v.Prerelease() != "" || v.Metadata() != "") {
+		return v.String()
+	}
+
+	s := make([]string, 0, precision)
+	for i := 0; i < precision; i++ {
+		n := 0
+		if i < len(segments) {
+			n = segments[i]
+		}
+		s = append(s, strconv.Itoa(n))
+	}
+	return strings.Join(s, ".")
+}
+
+// withinBump returns true if a version r is within the range of bump from
+// the current version c.
+func withinBump(bump string, c *version.Version, r *version.Version) bool {
+	cs, rs := c.Segments(), r.Segments()
+	switch bump {
+	case "patch":
+		return cs[0] == rs[0] && cs[1] == rs[1]
+	case "minor":
+		return cs[0] == rs[0]
+	case "major":
+		return true
+	default:
+		return false
+	}
+}
+
+// isDowngrade returns true if the new version is lower than the current one.
+// If either of them is a version constraint, its lower bound is compared.
+// If either of them cannot be interpreted as a version, it returns false.
+func isDowngrade(current string, newVersion string) bool {
+	c := minVersion(current)
+	n := minVersion(newVersion)
+	if c == nil || n == nil {
+		return false
+	}
+	return n.LessThan(c)
+}
+
+// minVersion returns the minimum version of a given exact version or version
+// constraint. If not found, it returns nil.
+func minVersion(s string) *version.Version {
+	if v, err := version.NewVersion(s); err == nil {
+		return v
+	}
+
+	if _, err := version.NewConstraint(s); err != nil {
+		return nil
+	}
+
+	v, err := version.NewVersion(lowerBound(s))
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// resolveVersion returns a new version to replace the current one according to
+// the version policy in the option. If the new version is lower than the
+// current one, it returns the current one unless a downgrade is allowed.
+func (mc *ModuleContext) resolveVersion(filename string, current string, target string) string {
+	o := mc.Option()
+	v := o.resolveVersion(current, target)
+	if mc.skipDowngrade(filename, current, v) {
+		return current
+	}
+	return v
+}
+
+// skipDowngrade returns true if the new version is lower than the current one
+// and a downgrade is not allowed. In this case, it also records a warning.
+func (mc *ModuleContext) skipDowngrade(filename string, current string, newVersion string) bool {
+	if mc.Option().allowDowngrade || !isDowngrade(current, newVersion) {
+		return false
+	}
+
+	mc.gc.addWarning(fmt.Sprintf("%s: skip downgrading from %s to %s. Use --allow-downgrade to force it", filename, current, newVersion))
+	return true
+}
+
+// VersionGap returns how far the current version constraint is behind the
+// latest version. It returns "major", "minor" or "patch" for the most
+// significant segment which differs, or an empty string if the constraint
+// already accepts the latest version. If either of them cannot be
+// interpreted as a version, it returns "unknown".
+func VersionGap(current string, latest string) string {
+	l, err := version.NewVersion(latest)
+	if err != nil {
+		return "unknown"
+	}
+
+	cs, err := version.NewConstraint(current)
+	if err != nil {
+		return "unknown"
+	}
+	if cs.Check(l) {
+		return ""
+	}
+
+	c := minVersion(current)
+	if c == nil {
+		// An upper bound only.
+		return "unknown"
+	}
+	if !c.LessThan(l) {
+		// Ahead of the latest.
+		return ""
+	}
+
+	lSegs, cSegs := l.Segments(), c.Segments()
+	switch {
+	case cSegs[0] != lSegs[0]:
+		return "major"
+	case cSegs[1] != lSegs[1]:
+		return "minor"
+	default:
+		return "patch"
+	}
+}
+
+// normalizeVersionConstraints merges a list of version constraints into a
+// single expression in the same format as the constraints attribute of the
+// dependency lock file generated by terraform init.
+// Terms are deduplicated and sorted by version. Each version is written with
+// all three segments, except for the pessimistic operator with two segments
+// such as ~> 1.2. The equal operator is omitted.
+// It returns an empty string if the constraints are empty or any of them
+// cannot be parsed.
+func normalizeVersionConstraints(constraints []string) string {
+	type term struct {
+		version *version.Version
+		str     string
+	}
+
+	terms := []term{}
+	for _, c := range constraints {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		if _, err := version.NewConstraint(c); err != nil {
+			log.Printf("[DEBUG] normalizeVersionConstraints: ignore constraint parse error: constraints = %#v, err = %s", constraints, err)
+			return ""
+		}
+
+		for _, s := range strings.Split(c, ",") {
+			m := constraintRegexp.FindStringSubmatch(s)
+			if m == nil {
+				return ""
+			}
+			operator, operand := m[2], m[4]
+			v, err := version.NewVersion(operand)
+			if err != nil {
+				return ""
+			}
+
+			segments := v.Segments()
+			str := fmt.Sprintf("%d.%d.%d", segments[0], segments[1], segments[2])
+			if operator == "~>" && versionPrecision(operand) <= 2 {
+				// ~> 1 is the same as ~> 1.0.
+				str = fmt.Sprintf("%d.%d", segments[0], segments[1])
+			}
+			if v.Prerelease() != "" {
+				str += "-" + v.Prerelease()
+			}
+			if v.Metadata() != "" {
+				str += "+" + v.Metadata()
+			}
+			if operator != "" && operator != "=" {
+				str = operator + " " + str
+			}
+
+			terms = append(terms, term{version: v, str: str})
+		}
+	}
+
+	sort.SliceStable(terms, func(i, j int) bool {
+		if c := terms[i].version.Compare(terms[j].version); c != 0 {
+			return c < 0
+		}
+		return terms[i].str < terms[j].str
+	})
+
+	ret := []string{}
+	for _, t := range terms {
+		if len(ret) > 0 && ret[len(ret)-1] == t.str {
+			continue
+		}
+		ret = append(ret, t.str)
+	}
+	return strings.Join(ret, ", ")
+}
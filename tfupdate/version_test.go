@@ -0,0 +1,343 @@
+package tfupdate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOptionResolveVersion(t *testing.T) {
+	releases := parseReleases([]string{"4.66.0", "4.66.1", "4.67.0", "5.0.0", "5.1.0", "5.2.0-beta1"})
+
+	cases := []struct {
+		desc              string
+		bump              string
+		preserveOperators bool
+		current           string
+		target            string
+		want              string
+	}{
+		{
+			desc:    "no bump",
+			bump:    "",
+			current: "4.66.0",
+			target:  "3.0.0",
+			want:    "3.0.0",
+		},
+		{
+			desc:    "patch",
+			bump:    "patch",
+			current: "4.66.0",
+			target:  "5.1.0",
+			want:    "4.66.1",
+		},
+		{
+			desc:    "minor",
+			bump:    "minor",
+			current: "4.66.0",
+			target:  "5.1.0",
+			want:    "4.67.0",
+		},
+		{
+			desc:    "major",
+			bump:    "major",
+			current: "4.66.0",
+			target:  "5.1.0",
+			want:    "5.1.0",
+		},
+		{
+			desc:    "already latest",
+			bump:    "patch",
+			current: "4.67.0",
+			target:  "5.1.0",
+			want:    "4.67.0",
+		},
+		{
+			desc:    "no release in the current line",
+			bump:    "minor",
+			current: "3.0.0",
+			target:  "5.1.0",
+			want:    "3.0.0",
+		},
+		{
+			desc:    "never downgrade",
+			bump:    "major",
+			current: "6.0.0",
+			target:  "5.1.0",
+			want:    "6.0.0",
+		},
+		{
+			desc:    "ignore pre-release",
+			bump:    "minor",
+			current: "5.0.0",
+			target:  "5.1.0",
+			want:    "5.1.0",
+		},
+		{
+			desc:    "constraint",
+			bump:    "minor",
+			current: "~> 4.66",
+			target:  "5.1.0",
+			want:    "~> 4.66",
+		},
+		{
+			desc:              "preserve operators for exact version",
+			preserveOperators: true,
+			current:           "4.66.0",
+			target:            "5.1.0",
+			want:              "5.1.0",
+		},
+		{
+			desc:              "preserve pessimistic operator",
+			preserveOperators: true,
+			current:           "~> 4.0",
+			target:            "5.1.0",
+			want:              "~> 5.1",
+		},
+		{
+			desc:              "preserve range",
+			preserveOperators: true,
+			current:           ">= 1.5, < 2.0",
+			target:            "2.3.0",
+			want:              ">= 2.3, < 3.0",
+		},
+		{
+			desc:              "keep upper bound including new version",
+			preserveOperators: true,
+			current:           ">=1.5.0,<3.0.0",
+			target:            "2.3.0",
+			want:              ">=2.3.0,<3.0.0",
+		},
+		{
+			desc:              "raise minor upper bound",
+			preserveOperators: true,
+			current:           ">= 1.5, < 1.6",
+			target:            "1.7.2",
+			want:              ">= 1.7, < 1.8",
+		},
+		{
+			desc:              "raise inclusive upper bound",
+			preserveOperators: true,
+			current:           ">= 1.5, <= 1.9",
+			target:            "2.0.1",
+			want:              ">= 2.0, <= 2.0",
+		},
+		{
+			desc:              "raise upper bound with more segments",
+			preserveOperators: true,
+			current:           ">= 1.0, < 1.2.3.4",
+			target:            "1.3.0",
+			want:              ">= 1.3, < 1.3.0.1",
+		},
+		{
+			desc:              "keep other operators",
+			preserveOperators: true,
+			current:           "> 1.0, != 1.5.0, ~> 1.4",
+			target:            "1.6.0",
+			want:              "> 1.0, != 1.5.0, ~> 1.6",
+		},
+		{
+			desc:              "pre-release",
+			preserveOperators: true,
+			current:           "~> 4.0",
+			target:            "5.0.0-beta1",
+			want:              "~> 5.0.0-beta1",
+		},
+		{
+			desc:              "target constraint",
+			preserveOperators: true,
+			current:           "~> 4.0",
+			target:            "~> 5.0",
+			want:              "~> 5.0",
+		},
+		{
+			desc:              "invalid constraint",
+			preserveOperators: true,
+			current:           "foo",
+			target:            "5.1.0",
+			want:              "5.1.0",
+		},
+		{
+			desc:              "bump with preserve operators",
+			bump:              "minor",
+			preserveOperators: true,
+			current:           "~> 4.66.0",
+			target:            "5.1.0",
+			want:              "~> 4.67.0",
+		},
+		{
+			desc:              "bump range",
+			bump:              "major",
+			preserveOperators: true,
+			current:           ">= 4.66, < 5.0",
+			target:            "5.1.0",
+			want:              ">= 5.1, < 6.0",
+		},
+		{
+			desc:              "bump without lower bound",
+			bump:              "major",
+			preserveOperators: true,
+			current:           "< 5.0",
+			target:            "5.1.0",
+			want:              "< 5.0",
+		},
+		{
+			desc:              "bump already latest",
+			bump:              "patch",
+			preserveOperators: true,
+			current:           "~> 4.67",
+			target:            "5.1.0",
+			want:              "~> 4.67",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			o := Option{
+				bump:              tc.bump,
+				releases:          releases,
+				preserveOperators: tc.preserveOperators,
+			}
+			got := o.resolveVersion(tc.current, tc.target)
+			if got != tc.want {
+				t.Errorf("resolveVersion() with current = %s, target = %s returns %s, but want = %s", tc.current, tc.target, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsDowngrade(t *testing.T) {
+	cases := []struct {
+		current    string
+		newVersion string
+		want       bool
+	}{
+		{current: "5.30.0", newVersion: "5.0.0", want: true},
+		{current: "5.0.0", newVersion: "5.30.0", want: false},
+		{current: "5.0.0", newVersion: "5.0.0", want: false},
+		{current: "~> 5.30", newVersion: "5.0.0", want: true},
+		{current: ">= 5.0, < 6.0", newVersion: "~> 5.1", want: false},
+		{current: "< 6.0", newVersion: "5.0.0", want: false},
+		{current: "", newVersion: "5.0.0", want: false},
+		{current: "foo", newVersion: "5.0.0", want: false},
+	}
+
+	for _, tc := range cases {
+		got := isDowngrade(tc.current, tc.newVersion)
+		if got != tc.want {
+			t.Errorf("isDowngrade() with current = %s, newVersion = %s returns %t, but want = %t", tc.current, tc.newVersion, got, tc.want)
+		}
+	}
+}
+
+func TestModuleContextResolveVersionDowngrade(t *testing.T) {
+	cases := []struct {
+		desc           string
+		allowDowngrade bool
+		current        string
+		target         string
+		want           string
+		warnings       []string
+	}{
+		{
+			desc:           "upgrade",
+			allowDowngrade: false,
+			current:        "5.0.0",
+			target:         "5.30.0",
+			want:           "5.30.0",
+			warnings:       nil,
+		},
+		{
+			desc:           "skip downgrade",
+			allowDowngrade: false,
+			current:        "5.30.0",
+			target:         "5.0.0",
+			want:           "5.30.0",
+			warnings:       []string{"main.tf: skip downgrading from 5.30.0 to 5.0.0. Use --allow-downgrade to force it"},
+		},
+		{
+			desc:           "allow downgrade",
+			allowDowngrade: true,
+			current:        "5.30.0",
+			target:         "5.0.0",
+			want:           "5.0.0",
+			warnings:       nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			gc := &GlobalContext{
+				option: Option{
+					allowDowngrade: tc.allowDowngrade,
+				},
+			}
+			mc := &ModuleContext{
+				gc: gc,
+			}
+
+			got := mc.resolveVersion("main.tf", tc.current, tc.target)
+			if got != tc.want {
+				t.Errorf("resolveVersion() returns %s, but want = %s", got, tc.want)
+			}
+
+			if !reflect.DeepEqual(gc.Warnings(), tc.warnings) {
+				t.Errorf("Warnings() returns %#v, but want = %#v", gc.Warnings(), tc.warnings)
+			}
+		})
+	}
+}
+
+func TestVersionGap(t *testing.T) {
+	cases := []struct {
+		current string
+		latest  string
+		want    string
+	}{
+		{current: "5.30.0", latest: "5.30.0", want: ""},
+		{current: "5.30.0", latest: "5.30.1", want: "patch"},
+		{current: "5.30.0", latest: "5.31.0", want: "minor"},
+		{current: "4.67.0", latest: "5.31.0", want: "major"},
+		{current: "~> 5.0", latest: "5.31.0", want: ""},
+		{current: "~> 4.0", latest: "5.31.0", want: "major"},
+		{current: ">= 5.0, < 5.30", latest: "5.31.0", want: "minor"},
+		{current: "6.0.0", latest: "5.31.0", want: ""},
+		{current: "< 5.0", latest: "5.31.0", want: "unknown"},
+		{current: "foo", latest: "5.31.0", want: "unknown"},
+		{current: "5.30.0", latest: "foo", want: "unknown"},
+	}
+
+	for _, tc := range cases {
+		got := VersionGap(tc.current, tc.latest)
+		if got != tc.want {
+			t.Errorf("VersionGap() with current = %s, latest = %s returns %s, but want = %s", tc.current, tc.latest, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizeVersionConstraints(t *testing.T) {
+	cases := []struct {
+		constraints []string
+		want        string
+	}{
+		{constraints: []string{"3.2.1"}, want: "3.2.1"},
+		{constraints: []string{"= 3.2.1"}, want: "3.2.1"},
+		{constraints: []string{"v3.2.1"}, want: "3.2.1"},
+		{constraints: []string{">= 4.0"}, want: ">= 4.0.0"},
+		{constraints: []string{"~> 5.0"}, want: "~> 5.0"},
+		{constraints: []string{"~> 5"}, want: "~> 5.0"},
+		{constraints: []string{"~> 5.0.1"}, want: "~> 5.0.1"},
+		{constraints: []string{"< 6.0, >= 4.0"}, want: ">= 4.0.0, < 6.0.0"},
+		{constraints: []string{"~> 5.0", ">= 4.67", "~> 5.0"}, want: ">= 4.67.0, ~> 5.0"},
+		{constraints: []string{">= 1.0.0-beta1", "!= 1.2.0"}, want: ">= 1.0.0-beta1, != 1.2.0"},
+		{constraints: []string{"", ">= 1.0"}, want: ">= 1.0.0"},
+		{constraints: []string{}, want: ""},
+		{constraints: []string{"foo"}, want: ""},
+	}
+
+	for _, tc := range cases {
+		got := normalizeVersionConstraints(tc.constraints)
+		if got != tc.want {
+			t.Errorf("normalizeVersionConstraints() with constraints = %#v returns %s, but want = %s", tc.constraints, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package tfupdate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// toolVersionsFilename is a filename of the version file shared by multiple
+// tools, which is used by asdf and mise.
+const toolVersionsFilename = ".tool-versions"
+
+// versionFileTools is a map from a filename of the version file dedicated to
+// a tool to the tool name.
+var versionFileTools = map[string]string{
+	".terraform-version": "terraform",
+	".opentofu-version":  "opentofu",
+}
+
+// toolVersionsRegexps is a map from a tool name to a regular expression for
+// a line of the tool in the .tool-versions. The second submatch is the first
+// version of the tool.
+var toolVersionsRegexps = map[string]*regexp.Regexp{
+	"terraform": regexp.MustCompile(`^([ \t]*terraform[ \t]+)([^ \t\r\n#]+)`),
+	"opentofu":  regexp.MustCompile(`^([ \t]*opentofu[ \t]+)([^ \t\r\n#]+)`),
+}
+
+// isVersionManagerFile returns true if a given filename is a version file used
+// by version managers such as tfenv, tofuenv, asdf and mise.
+func isVersionManagerFile(filename string) bool {
+	name := filepath.Base(filename)
+	_, ok := versionFileTools[name]
+	return ok || name == toolVersionsFilename
+}
+
+// versionFileUpdater is an optional interface for updaters which support
+// version files of version managers.
+type versionFileUpdater interface {
+	// updateVersionFile updates a version in the version file and returns the
+	// updated contents.
+	updateVersionFile(mc *ModuleContext, filename string, src []byte) []byte
+}
+
+// UpdateVersionManagerFile reads a version file of version managers from
+// io.Reader, updates the version of the tool and writes updated contents to
+// io.Writer. The following formats are supported:
+//   - .terraform-version (tfenv) and .opentofu-version (tofuenv), which
+//     contain only a version.
+//   - .tool-versions (asdf and mise), which contains a tool name and versions
+//     in each line.
+//
+// If contents changed successfully, it returns true, or otherwise returns false.
+// If an error occurs, nothing is written to the output stream.
+func UpdateVersionManagerFile(_ context.Context, mc *ModuleContext, r io.Reader, w io.Writer, filename string) (bool, error) {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return false, fmt.Errorf("failed to read input: %s", err)
+	}
+
+	output := input
+	if u, ok := mc.Updater().(versionFileUpdater); ok {
+		output = u.updateVersionFile(mc, filename, input)
+	}
+
+	if _, err := w.Write(output); err != nil {
+		return false, fmt.Errorf("failed to write output: %s", err)
+	}
+
+	isUpdated := !bytes.Equal(input, output)
+	return isUpdated, nil
+}
+
+// updateToolVersion updates a version of a given tool in the version file.
+// The tool is a name in the .tool-versions, and the versionFile is a filename
+// dedicated to the tool such as .terraform-version.
+func updateToolVersion(mc *ModuleContext, filename string, src []byte, tool string, versionFile string, target string) []byte {
+	switch filepath.Base(filename) {
+	case versionFile:
+		return updateSingleVersionFile(mc, filename, src, tool, target)
+	case toolVersionsFilename:
+		return updateToolVersionsFile(mc, filename, src, tool, target)
+	default:
+		return src
+	}
+}
+
+// updateSingleVersionFile updates a version file which contains only a
+// version such as .terraform-version.
+func updateSingleVersionFile(mc *ModuleContext, filename string, src []byte, tool string, target string) []byte {
+	oldVersion := strings.TrimSpace(string(src))
+	newVersion, ok := resolveExactVersion(mc, filename, oldVersion, target)
+	if !ok {
+		return src
+	}
+
+	mc.recordChange(newChange(filename, "", nil, tool, oldVersion, newVersion))
+	return bytes.Replace(src, []byte(oldVersion), []byte(newVersion), 1)
+}
+
+// updateToolVersionsFile updates a version of a given tool in the
+// .tool-versions. If multiple versions are listed for fallback, only the first
+// one is updated.
+func updateToolVersionsFile(mc *ModuleContext, filename string, src []byte, tool string, target string) []byte {
+	re, ok := toolVersionsRegexps[tool]
+	if !ok {
+		return src
+	}
+
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	for i, line := range lines {
+		matched := re.FindSubmatchIndex(line)
+		if matched == nil {
+			continue
+		}
+
+		oldVersion := string(line[matched[4]:matched[5]])
+		newVersion, ok := resolveExactVersion(mc, filename, oldVersion, target)
+		if !ok {
+			continue
+		}
+
+		mc.recordChange(newChange(filename, "", nil, tool, oldVersion, newVersion))
+		updated := []byte{}
+		updated = append(updated, line[:matched[4]]...)
+		updated = append(updated, newVersion...)
+		updated = append(updated, line[matched[5]:]...)
+		lines[i] = updated
+	}
+
+	return bytes.Join(lines, nil)
+}
+
+// resolveExactVersion returns a new version for the version file.
+// Version managers require an exact version, so it returns false if either
+// the current or new value is not a version. The current value may be a
+// special keyword such as latest or min-required, which is kept as it is.
+// If the new value is a version constraint, it also records a warning.
+func resolveExactVersion(mc *ModuleContext, filename string, current string, target string) (string, bool) {
+	if _, err := version.NewVersion(current); err != nil {
+		return "", false
+	}
+
+	v := mc.resolveVersion(filename, current, target)
+	if _, err := version.NewVersion(v); err != nil {
+		mc.gc.addWarning(fmt.Sprintf("%s: skip updating to %s. A version file requires an exact version", filename, v))
+		return "", false
+	}
+
+	return v, true
+}
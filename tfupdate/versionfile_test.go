@@ -0,0 +1,154 @@
+package tfupdate
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestUpdateVersionManagerFile(t *testing.T) {
+	cases := []struct {
+		filename     string
+		src          string
+		o            Option
+		want         string
+		isUpdated    bool
+		wantWarnings []string
+	}{
+		{
+			filename: ".terraform-version",
+			src:      "1.5.7\n",
+			o: Option{
+				updateType: "terraform",
+				version:    "1.6.0",
+			},
+			want:         "1.6.0\n",
+			isUpdated:    true,
+			wantWarnings: nil,
+		},
+		{
+			filename: ".terraform-version",
+			src:      "latest:^1.5\n",
+			o: Option{
+				updateType: "terraform",
+				version:    "1.6.0",
+			},
+			want:         "latest:^1.5\n",
+			isUpdated:    false,
+			wantWarnings: nil,
+		},
+		{
+			filename: ".terraform-version",
+			src:      "1.5.7\n",
+			o: Option{
+				updateType: "terraform",
+				version:    "~> 1.6",
+			},
+			want:         "1.5.7\n",
+			isUpdated:    false,
+			wantWarnings: []string{".terraform-version: skip updating to ~> 1.6. A version file requires an exact version"},
+		},
+		{
+			filename: ".terraform-version",
+			src:      "1.5.7\n",
+			o: Option{
+				updateType: "opentofu",
+				version:    "1.8.0",
+			},
+			want:         "1.5.7\n",
+			isUpdated:    false,
+			wantWarnings: nil,
+		},
+		{
+			filename: ".opentofu-version",
+			src:      "1.7.0",
+			o: Option{
+				updateType: "opentofu",
+				version:    "1.8.0",
+			},
+			want:         "1.8.0",
+			isUpdated:    true,
+			wantWarnings: nil,
+		},
+		{
+			filename: ".tool-versions",
+			src: `# tools
+nodejs 20.11.0
+terraform   1.5.7 1.4.0 # fallback
+opentofu 1.7.0
+`,
+			o: Option{
+				updateType: "terraform",
+				version:    "1.6.0",
+			},
+			want: `# tools
+nodejs 20.11.0
+terraform   1.6.0 1.4.0 # fallback
+opentofu 1.7.0
+`,
+			isUpdated:    true,
+			wantWarnings: nil,
+		},
+		{
+			filename: "env/.tool-versions",
+			src: `terraform 1.5.7
+opentofu 1.7.0`,
+			o: Option{
+				updateType: "opentofu",
+				version:    "1.8.0",
+			},
+			want: `terraform 1.5.7
+opentofu 1.8.0`,
+			isUpdated:    true,
+			wantWarnings: nil,
+		},
+		{
+			filename: ".tool-versions",
+			src: `terraform 1.5.7
+`,
+			o: Option{
+				updateType: "provider",
+				name:       "aws",
+				version:    "5.0.0",
+			},
+			want: `terraform 1.5.7
+`,
+			isUpdated:    false,
+			wantWarnings: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		gc, err := NewGlobalContext(afero.NewMemMapFs(), tc.o)
+		if err != nil {
+			t.Fatalf("failed to new global context: %s", err)
+		}
+
+		mc, err := NewModuleContext(".", gc)
+		if err != nil {
+			t.Fatalf("failed to new module context: %s", err)
+		}
+
+		r := bytes.NewBufferString(tc.src)
+		w := &bytes.Buffer{}
+		isUpdated, err := UpdateVersionManagerFile(context.Background(), mc, r, w, tc.filename)
+		if err != nil {
+			t.Fatalf("UpdateVersionManagerFile() with filename = %s, src = %s, o = %#v returns unexpected err: %+v", tc.filename, tc.src, tc.o, err)
+		}
+
+		if isUpdated != tc.isUpdated {
+			t.Errorf("UpdateVersionManagerFile() with filename = %s, src = %s, o = %#v returns unexpected isUpdated, got = %t, want = %t", tc.filename, tc.src, tc.o, isUpdated, tc.isUpdated)
+		}
+
+		if got := w.String(); got != tc.want {
+			t.Errorf("UpdateVersionManagerFile() with filename = %s, src = %s, o = %#v returns %s, but want = %s", tc.filename, tc.src, tc.o, got, tc.want)
+		}
+
+		if got := gc.Warnings(); !reflect.DeepEqual(got, tc.wantWarnings) {
+			t.Errorf("UpdateVersionManagerFile() with filename = %s, src = %s, o = %#v records warnings %#v, but want = %#v", tc.filename, tc.src, tc.o, got, tc.wantWarnings)
+		}
+	}
+}